@@ -440,6 +440,21 @@ func (m Model) didSelectFile(msg tea.Msg) (bool, string) {
 	return false, ""
 }
 
+// HighlightedFile returns the path of the file under the cursor, if it's a file that can be selected.
+func (m Model) HighlightedFile() (bool, string) {
+	if len(m.files) == 0 {
+		return false, ""
+	}
+
+	f := m.files[m.selected]
+	file := path.Join(m.CurrentDirectory, f.Name())
+	if f.IsDir() || !m.FileAllowed || !m.canSelect(file) {
+		return false, ""
+	}
+
+	return true, file
+}
+
 func (m Model) canSelect(file string) bool {
 	if len(m.AllowedTypes) <= 0 {
 		return true
@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+)
+
+// Client is used for every request to a clock. Credentials in the host URL are sent as basic auth by net/http.
+var Client = &http.Client{
+	Timeout: time.Second * 10,
+}
+
+// ConfigureTLS sets up Client for clocks served over HTTPS, e.g. behind a reverse proxy.
+// caFile adds a PEM encoded certificate authority to the trusted roots, insecure skips verification entirely.
+func ConfigureTLS(caFile string, insecure bool) error {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: insecure,
+	}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return err
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("no certificates found in " + caFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	Client.Transport = transport
+
+	return nil
+}
+
+type DeviceStats struct {
+	Battery     int     `json:"bat"`
+	Lux         float64 `json:"lux"`
+	Brightness  int     `json:"bri"`
+	Temperature float64 `json:"temp"`
+	Humidity    float64 `json:"hum"`
+	FreeHeap    int     `json:"ram"`
+	Uptime      int     `json:"uptime"`
+	WifiSignal  int     `json:"wifi_signal"`
+	Version     string  `json:"version"`
+	App         string  `json:"app"`
+	UID         string  `json:"uid"`
+}
+
+func GetDeviceStats() (*DeviceStats, error) {
+	var stats DeviceStats
+	err := apiGetJSON("/api/stats", &stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+func GetDeviceSettings() (map[string]any, error) {
+	var settings map[string]any
+	err := apiGetJSON("/api/settings", &settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+func SetDeviceSettings(settings map[string]any) error {
+	return apiPostJSON("/api/settings", settings)
+}
+
+// GetAppLoop returns the names of the apps in the clock's loop, in display order.
+func GetAppLoop() ([]string, error) {
+	var loop map[string]int
+	err := apiGetJSON("/api/loop", &loop)
+	if err != nil {
+		return nil, err
+	}
+
+	apps := make([]string, 0, len(loop))
+	for name := range loop {
+		apps = append(apps, name)
+	}
+
+	sort.Slice(apps, func(i, j int) bool {
+		return loop[apps[i]] < loop[apps[j]]
+	})
+
+	return apps, nil
+}
+
+func SwitchApp(name string) error {
+	return apiPostJSON("/api/switch", map[string]string{"name": name})
+}
+
+func DismissNotification(host string) error {
+	return hostPostJSON(host, "/api/notify/dismiss", nil)
+}
+
+func apiGetJSON(path string, v any) error {
+	return hostGetJSON(Host, path, v)
+}
+
+func hostGetJSON(host string, path string, v any) error {
+	resp, err := Client.Get(host + path)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %s", path, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func apiPostJSON(path string, v any) error {
+	return hostPostJSON(Host, path, v)
+}
+
+func hostPostJSON(host string, path string, v any) error {
+	var body io.Reader = http.NoBody
+	if v != nil {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	resp, err := Client.Post(host+path, "application/json", body)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("POST %s: unexpected status %s", path, resp.Status)
+	}
+
+	return nil
+}
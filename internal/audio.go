@@ -0,0 +1,301 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AudioPlayer is the program that plays the soundtrack of videos, ffplay or mpv. Audio is off while it's empty.
+var AudioPlayer string
+
+// AudioOffset delays the audio behind the LEDs, to make up for the time frames take to reach the clock.
+// A negative offset plays the audio ahead of them.
+var AudioOffset time.Duration
+
+// The soundtrack is kept next to the .pxlstrm, as <video>.pxlstrm.mka
+const audioFileExt = ".mka"
+
+// Audio plays a soundtrack that follows a Player.
+type Audio interface {
+	// Play starts the soundtrack from position, or moves it there if it's already playing.
+	Play(position time.Duration) error
+	Pause() error
+	Close() error
+}
+
+// FindAudio returns the soundtrack of a video or .pxlstrm file, extracting it from the source video the first time.
+func FindAudio(fl FileLocation) (FileLocation, error) {
+	if fl.System != OS_FS {
+		return FileLocation{}, errors.New("audio is only available for files on disk")
+	}
+
+	source := FileLocation{
+		System: fl.System,
+		Path:   strings.TrimSuffix(fl.Path, pixelstreamFileExt),
+	}
+	audio := FileLocation{
+		System: fl.System,
+		Path:   source.Path + pixelstreamFileExt + audioFileExt,
+	}
+
+	if _, err := fs.Stat(audio.System, audio.Path); err == nil {
+		return audio, nil
+	}
+
+	if _, err := fs.Stat(source.System, source.Path); err != nil {
+		return FileLocation{}, errors.New("the source video isn't next to the .pxlstrm file")
+	}
+
+	return audio, ExtractAudio(source, audio)
+}
+
+// ExtractAudio copies the first audio track of source into a Matroska file at dest, without re-encoding it.
+func ExtractAudio(source FileLocation, dest FileLocation) error {
+	cmd := exec.Command("ffmpeg", "-y", "-loglevel", "error", "-i", source.ToOSPath(), "-map", "0:a:0", "-vn", "-c:a", "copy", dest.ToOSPath())
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		os.Remove(dest.ToOSPath())
+		return fmt.Errorf("couldn't extract the audio: %s", strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// Seeking comes in bursts, such as dragging along the progress bar, so the audio is only restarted once it has settled for this long
+const audioRestartDelay = time.Millisecond * 100
+
+// audioFollower passes what a Player does on to its Audio from a goroutine of its own, so starting and stopping an
+// external player never holds up the Player. Only the latest request is kept, since it's the only one that matters.
+type audioFollower struct {
+	audio    Audio
+	position func() time.Duration
+	requests chan bool
+	closed   chan struct{}
+	done     chan struct{}
+	close    sync.Once
+
+	mutex sync.Mutex
+	err   error
+}
+
+// newAudioFollower makes audio follow a player, which is asked for its position whenever the audio is played.
+func newAudioFollower(audio Audio, position func() time.Duration) *audioFollower {
+	f := &audioFollower{
+		audio:    audio,
+		position: position,
+		requests: make(chan bool, 1),
+		closed:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go f.run()
+
+	return f
+}
+
+func (f *audioFollower) play() {
+	f.request(true)
+}
+
+func (f *audioFollower) pause() {
+	f.request(false)
+}
+
+// request replaces the request waiting to be handled, if there is one.
+func (f *audioFollower) request(play bool) {
+	for {
+		select {
+		case f.requests <- play:
+			return
+		default:
+		}
+
+		select {
+		case <-f.requests:
+		default:
+		}
+	}
+}
+
+// Err returns why the audio last failed to play or pause, or nil if it didn't.
+func (f *audioFollower) Err() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.err
+}
+
+// Close stops following the player, dropping any request that's still waiting, and closes the audio.
+func (f *audioFollower) Close() {
+	f.close.Do(func() {
+		close(f.closed)
+		<-f.done
+		f.audio.Close()
+	})
+}
+
+func (f *audioFollower) run() {
+	defer close(f.done)
+
+	for {
+		var play bool
+		select {
+		case play = <-f.requests:
+		case <-f.closed:
+			return
+		}
+
+	settle:
+		for play {
+			timer := time.NewTimer(audioRestartDelay)
+			select {
+			case play = <-f.requests:
+				timer.Stop()
+			case <-timer.C:
+				break settle
+			case <-f.closed:
+				timer.Stop()
+				return
+			}
+		}
+
+		var err error
+		if play {
+			err = f.audio.Play(f.position())
+		} else {
+			err = f.audio.Pause()
+		}
+
+		f.mutex.Lock()
+		f.err = err
+		f.mutex.Unlock()
+	}
+}
+
+// ExternalAudio plays a soundtrack with ffplay or mpv. Neither can be controlled once started,
+// so the player is restarted at the new position whenever playback starts or seeks, and killed on pause.
+type ExternalAudio struct {
+	command string
+	file    string
+
+	mutex  sync.Mutex
+	offset time.Duration
+	// Counts restarts, so a delayed start that was overtaken by a pause or seek does nothing
+	generation int
+	process    *os.Process
+	exited     chan struct{}
+}
+
+// NewExternalAudio plays file with command, which is ffplay or mpv.
+func NewExternalAudio(command string, file FileLocation, offset time.Duration) (*ExternalAudio, error) {
+	command, err := exec.LookPath(command)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExternalAudio{
+		command: command,
+		file:    file.ToOSPath(),
+		offset:  offset,
+	}, nil
+}
+
+func (a *ExternalAudio) Play(position time.Duration) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.stop()
+
+	start := position - a.offset
+	if start >= 0 {
+		return a.start(start)
+	}
+
+	// The LEDs haven't caught up with the offset yet, so the audio starts from the beginning once they have
+	generation := a.generation
+	time.AfterFunc(-start, func() {
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+
+		if a.generation == generation {
+			a.start(0)
+		}
+	})
+
+	return nil
+}
+
+func (a *ExternalAudio) Pause() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.stop()
+	return nil
+}
+
+func (a *ExternalAudio) Close() error {
+	return a.Pause()
+}
+
+func (a *ExternalAudio) Offset() time.Duration {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.offset
+}
+
+// SetOffset changes the offset for the next time the audio is played or moved.
+func (a *ExternalAudio) SetOffset(offset time.Duration) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.offset = offset
+}
+
+func (a *ExternalAudio) args(start time.Duration) []string {
+	seconds := strconv.FormatFloat(start.Seconds(), 'f', 3, 64)
+
+	if strings.TrimSuffix(filepath.Base(a.command), ".exe") == "mpv" {
+		return []string{"--no-video", "--no-terminal", "--start=" + seconds, a.file}
+	}
+
+	return []string{"-nodisp", "-autoexit", "-loglevel", "quiet", "-ss", seconds, a.file}
+}
+
+func (a *ExternalAudio) start(start time.Duration) error {
+	cmd := exec.Command(a.command, a.args(start)...)
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	// Waited on straight away, so a player that reaches the end by itself doesn't linger
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	a.process = cmd.Process
+	a.exited = exited
+	return nil
+}
+
+func (a *ExternalAudio) stop() {
+	a.generation++
+
+	if a.process == nil {
+		return
+	}
+
+	a.process.Kill()
+	<-a.exited
+	a.process = nil
+}
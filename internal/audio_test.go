@@ -0,0 +1,209 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newFakeAudioPlayer writes a script named name that logs its arguments and then plays for a while, returning it and its log.
+func newFakeAudioPlayer(t *testing.T, name string) (string, string) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake audio player is a shell script")
+	}
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	script := filepath.Join(dir, name)
+
+	err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\" >> "+log+"\nexec sleep 10\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return script, log
+}
+
+// waitForLines waits for the log to have count lines, and returns them.
+func waitForLines(t *testing.T, log string, count int) []string {
+	t.Helper()
+
+	var lines []string
+	for deadline := time.Now().Add(time.Second * 2); time.Now().Before(deadline); time.Sleep(time.Millisecond * 10) {
+		data, _ := os.ReadFile(log)
+		lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(data) > 0 && len(lines) >= count {
+			return lines
+		}
+	}
+
+	t.Fatalf("expected the audio player to start %d times, got %q", count, lines)
+	return nil
+}
+
+func TestExternalAudio(t *testing.T) {
+	script, log := newFakeAudioPlayer(t, "ffplay")
+	file := FromOSPath(filepath.Join(t.TempDir(), "video.mp4.pxlstrm.mka"))
+
+	audio, err := NewExternalAudio(script, file, time.Millisecond*200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audio.Close()
+
+	audio.Play(time.Second * 10)
+	lines := waitForLines(t, log, 1)
+	if !strings.Contains(lines[0], "-nodisp") || !strings.Contains(lines[0], "-ss 9.800 ") {
+		t.Errorf("expected ffplay to start 200ms behind the LEDs, got %q", lines[0])
+	}
+
+	// Playing again moves the audio by restarting the player
+	audio.Play(time.Second * 20)
+	lines = waitForLines(t, log, 2)
+	if !strings.Contains(lines[1], "-ss 19.800 ") {
+		t.Errorf("expected the audio to move to 19.8s, got %q", lines[1])
+	}
+
+	// Before the LEDs reach the offset, the audio waits and then starts from the beginning
+	audio.Play(time.Millisecond * 100)
+	time.Sleep(time.Millisecond * 50)
+	if lines := waitForLines(t, log, 2); len(lines) != 2 {
+		t.Errorf("expected the audio to wait for the offset, got %q", lines)
+	}
+
+	lines = waitForLines(t, log, 3)
+	if !strings.Contains(lines[2], "-ss 0.000 ") {
+		t.Errorf("expected the audio to start from the beginning, got %q", lines[2])
+	}
+
+	// A start that's still waiting doesn't happen after a pause
+	audio.Play(0)
+	audio.Pause()
+	time.Sleep(time.Millisecond * 300)
+	if lines := waitForLines(t, log, 3); len(lines) != 3 {
+		t.Errorf("expected pausing to cancel the delayed start, got %q", lines)
+	}
+}
+
+func TestExternalAudioMPV(t *testing.T) {
+	script, log := newFakeAudioPlayer(t, "mpv")
+	file := FromOSPath(filepath.Join(t.TempDir(), "video.mp4.pxlstrm.mka"))
+
+	audio, err := NewExternalAudio(script, file, -time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audio.Close()
+
+	audio.Play(time.Second * 5)
+	if lines := waitForLines(t, log, 1); !strings.Contains(lines[0], "--no-video") || !strings.Contains(lines[0], "--start=6.000 ") {
+		t.Errorf("expected mpv to start a second ahead of the LEDs, got %q", lines[0])
+	}
+}
+
+// recordingAudio records the positions the player plays it from, with -1 for a pause.
+type recordingAudio struct {
+	mutex     sync.Mutex
+	positions []time.Duration
+	closed    bool
+	err       error
+}
+
+func (a *recordingAudio) Play(position time.Duration) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.positions = append(a.positions, position)
+	return a.err
+}
+
+func (a *recordingAudio) Pause() error {
+	a.Play(-1)
+	return nil
+}
+
+func (a *recordingAudio) Close() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.closed = true
+	return nil
+}
+
+// waitFor waits for the audio to have been played and paused as expected, which happens in the background.
+func (a *recordingAudio) waitFor(t *testing.T, expected ...time.Duration) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second * 2); ; time.Sleep(time.Millisecond * 5) {
+		a.mutex.Lock()
+		positions := slices.Clone(a.positions)
+		a.mutex.Unlock()
+
+		if slices.Equal(positions, expected) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the audio to follow playback as %v, got %v", expected, positions)
+		}
+	}
+}
+
+func TestPlayerDrivesAudio(t *testing.T) {
+	clock := &fakeClock{time: time.Now()}
+	audio := &recordingAudio{}
+	p := newPlayer(newTestStream(40, 10), &recordingTransport{}, clock.now)
+	p.SetAudio(audio)
+
+	p.Play()
+	audio.waitFor(t, 0)
+
+	// A burst of seeks restarts the audio once, where it ended up
+	clock.advance(time.Second)
+	for i := 1; i <= 10; i++ {
+		p.Seek(time.Millisecond * 300 * time.Duration(i))
+	}
+	audio.waitFor(t, 0, time.Second*3)
+
+	p.Pause()
+	p.Seek(time.Second * 2)
+	audio.waitFor(t, 0, time.Second*3, -1)
+
+	p.Play()
+	audio.waitFor(t, 0, time.Second*3, -1, time.Second*2)
+
+	clock.advance(time.Minute)
+	p.step()
+	audio.waitFor(t, 0, time.Second*3, -1, time.Second*2, -1)
+
+	close(p.done)
+	p.Close()
+	if !audio.closed {
+		t.Error("expected closing the player to close the audio")
+	}
+}
+
+func TestPlayerReportsAudioErrors(t *testing.T) {
+	clock := &fakeClock{time: time.Now()}
+	audio := &recordingAudio{err: errors.New("no sound card")}
+	p := newPlayer(newTestStream(40, 10), &recordingTransport{}, clock.now)
+	p.SetAudio(audio)
+
+	p.Play()
+	audio.waitFor(t, 0)
+	for deadline := time.Now().Add(time.Second * 2); p.AudioErr() == nil; time.Sleep(time.Millisecond * 5) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the audio's error to be reported")
+		}
+	}
+
+	close(p.done)
+	p.Close()
+}
@@ -0,0 +1,384 @@
+package internal
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type controlKind int
+
+const (
+	controlSlider controlKind = iota
+	controlToggle
+	controlChoice
+)
+
+type deviceControl struct {
+	label   string
+	setting string
+	// Index into an RGB array setting, or -1 for plain settings
+	channel int
+	kind    controlKind
+	min     int
+	max     int
+	step    int
+	choices []string
+}
+
+var transitionEffects = []string{"Random", "Slide", "Dim", "Zoom", "Rotate", "Pixelate", "Curtain", "Ripple", "Blink", "Reload", "Fade"}
+
+var deviceControls = []deviceControl{
+	{label: "Power", setting: "MATP", channel: -1, kind: controlToggle},
+	{label: "Brightness", setting: "BRI", channel: -1, kind: controlSlider, min: 0, max: 255, step: 8},
+	{label: "Auto brightness", setting: "ABRI", channel: -1, kind: controlToggle},
+	{label: "Color correction R", setting: "CCORRECT", channel: 0, kind: controlSlider, min: 0, max: 255, step: 8},
+	{label: "Color correction G", setting: "CCORRECT", channel: 1, kind: controlSlider, min: 0, max: 255, step: 8},
+	{label: "Color correction B", setting: "CCORRECT", channel: 2, kind: controlSlider, min: 0, max: 255, step: 8},
+	{label: "Color temperature R", setting: "CTEMP", channel: 0, kind: controlSlider, min: 0, max: 255, step: 8},
+	{label: "Color temperature G", setting: "CTEMP", channel: 1, kind: controlSlider, min: 0, max: 255, step: 8},
+	{label: "Color temperature B", setting: "CTEMP", channel: 2, kind: controlSlider, min: 0, max: 255, step: 8},
+	{label: "Auto transition", setting: "ATRANS", channel: -1, kind: controlToggle},
+	{label: "Transition effect", setting: "TEFF", channel: -1, kind: controlChoice, choices: transitionEffects},
+	{label: "Transition speed", setting: "TSPEED", channel: -1, kind: controlSlider, min: 0, max: 2000, step: 50},
+	{label: "App time", setting: "ATIME", channel: -1, kind: controlSlider, min: 1, max: 120, step: 1},
+}
+
+type ControlMode struct {
+	settings map[string]any
+	// The settings the device is known to have, which settings are put back to when saving them fails
+	saved       map[string]any
+	saving      int
+	stats       *DeviceStats
+	selected    int
+	status      string
+	err         error
+	keymap      ControlModeKeymap
+	help        help.Model
+	progress    progress.Model
+	settingLock *CmdLock
+}
+
+type ControlModeKeymap struct {
+	up       key.Binding
+	down     key.Binding
+	decrease key.Binding
+	increase key.Binding
+	toggle   key.Binding
+	refresh  key.Binding
+	quit     key.Binding
+}
+
+func NewControlMode() ControlMode {
+	return ControlMode{
+		keymap: ControlModeKeymap{
+			up: key.NewBinding(
+				key.WithKeys("up", "k"),
+				key.WithHelp("↑/k", "up"),
+			),
+			down: key.NewBinding(
+				key.WithKeys("down", "j"),
+				key.WithHelp("↓/j", "down"),
+			),
+			decrease: key.NewBinding(
+				key.WithKeys("left", "h"),
+				key.WithHelp("←/h", "decrease"),
+			),
+			increase: key.NewBinding(
+				key.WithKeys("right", "l"),
+				key.WithHelp("→/l", "increase"),
+			),
+			toggle: key.NewBinding(
+				key.WithKeys(" ", "enter"),
+				key.WithHelp("space", "toggle"),
+			),
+			refresh: key.NewBinding(
+				key.WithKeys("r"),
+				key.WithHelp("r", "refresh"),
+			),
+			quit: key.NewBinding(
+				key.WithKeys("ctrl+c", "q"),
+				key.WithHelp("q", "quit"),
+			),
+		},
+		help:        help.New(),
+		progress:    progress.New(progress.WithoutPercentage(), progress.WithWidth(30), progress.WithScaledGradient("#FF7CCB", "#FDFF8C")),
+		settingLock: &CmdLock{},
+	}
+}
+
+func (m ControlMode) Init() tea.Cmd {
+	return m.fetchDevice
+}
+
+func (m ControlMode) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+
+		case "q":
+			return NewMenuMode(), nil
+		}
+
+		switch {
+		case key.Matches(msg, m.keymap.refresh):
+			m.status = "Refreshing..."
+			return m, m.fetchDevice
+		case key.Matches(msg, m.keymap.up):
+			m.selected = (m.selected - 1 + len(deviceControls)) % len(deviceControls)
+		case key.Matches(msg, m.keymap.down):
+			m.selected = (m.selected + 1) % len(deviceControls)
+		case key.Matches(msg, m.keymap.decrease):
+			return m.adjust(-1)
+		case key.Matches(msg, m.keymap.increase):
+			return m.adjust(1)
+		case key.Matches(msg, m.keymap.toggle):
+			return m.adjust(0)
+		}
+
+	case controlFetchMsg:
+		m.err = msg.err
+		if msg.err == nil {
+			m.settings = msg.settings
+			m.saved = maps.Clone(msg.settings)
+			m.stats = msg.stats
+			m.status = ""
+		}
+
+	case controlSavedMsg:
+		m.saving--
+		if msg.err != nil {
+			m.status = "Failed to save " + msg.label + ": " + msg.err.Error()
+		} else {
+			m.status = "Saved " + msg.label
+			maps.Copy(m.saved, msg.update)
+			if msg.stats != nil {
+				m.stats = msg.stats
+			}
+		}
+
+		// A failed value is taken back straight away. Otherwise the device's values are shown once every save is in,
+		// so values still being saved aren't undone.
+		if msg.err != nil || m.saving == 0 {
+			for k := range msg.update {
+				m.settings[k] = m.saved[k]
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// adjust moves the selected control by direction steps. A direction of 0 toggles or cycles it.
+func (m ControlMode) adjust(direction int) (tea.Model, tea.Cmd) {
+	if m.settings == nil {
+		return m, nil
+	}
+
+	control := deviceControls[m.selected]
+	value, ok := control.value(m.settings)
+	if !ok {
+		m.status = control.label + " is not supported by this device"
+		return m, nil
+	}
+
+	switch control.kind {
+	case controlToggle:
+		value = !value.(bool)
+	case controlChoice:
+		if direction == 0 {
+			direction = 1
+		}
+		value = (value.(int) + direction + len(control.choices)) % len(control.choices)
+	case controlSlider:
+		if direction == 0 {
+			return m, nil
+		}
+		value = min(max(value.(int)+direction*control.step, control.min), control.max)
+	}
+
+	update := control.update(m.settings, value)
+	for k, v := range update {
+		m.settings[k] = v
+	}
+
+	m.status = "Saving " + control.label + "..."
+	m.saving++
+
+	return m, m.settingLock.Lock(func() tea.Msg {
+		err := SetDeviceSettings(update)
+		if err != nil {
+			return controlSavedMsg{label: control.label, update: update, err: err}
+		}
+
+		// The stats reflect what the device actually applied, e.g. brightness under auto brightness
+		stats, _ := GetDeviceStats()
+		return controlSavedMsg{label: control.label, update: update, stats: stats}
+	})
+}
+
+func (m ControlMode) View() string {
+	var s strings.Builder
+
+	s.WriteString("\nControl Panel - ")
+	s.WriteString(RedactHost(Host))
+	s.WriteString("\n\n")
+
+	if m.err != nil {
+		s.WriteString("Error reading device settings: ")
+		s.WriteString(m.err.Error())
+		s.WriteString("\n")
+		s.WriteString(m.helpView())
+		return s.String()
+	}
+
+	if m.settings == nil {
+		s.WriteString("Loading device settings...\n")
+		s.WriteString(m.helpView())
+		return s.String()
+	}
+
+	if m.stats != nil {
+		s.WriteString(helpStyle(fmt.Sprintf("App: %s  Brightness: %d  Lux: %.0f  Firmware: %s", m.stats.App, m.stats.Brightness, m.stats.Lux, m.stats.Version)))
+		s.WriteString("\n\n")
+	}
+
+	for i, control := range deviceControls {
+		label := fmt.Sprintf("%-20s", control.label)
+		if i == m.selected {
+			s.WriteString(selectedItemStyle.Render("> " + label))
+		} else {
+			s.WriteString(itemStyle.Render(label))
+		}
+		s.WriteRune(' ')
+		s.WriteString(m.controlView(control))
+		s.WriteRune('\n')
+	}
+
+	if m.status != "" {
+		s.WriteRune('\n')
+		s.WriteString(m.status)
+		s.WriteRune('\n')
+	}
+
+	s.WriteString(m.helpView())
+
+	return s.String()
+}
+
+func (m ControlMode) controlView(control deviceControl) string {
+	value, ok := control.value(m.settings)
+	if !ok {
+		return helpStyle("unsupported")
+	}
+
+	switch control.kind {
+	case controlToggle:
+		if value.(bool) {
+			return "on"
+		}
+		return "off"
+	case controlChoice:
+		return "< " + control.choices[value.(int)] + " >"
+	default:
+		percent := float64(value.(int)-control.min) / float64(control.max-control.min)
+		return m.progress.ViewAs(percent) + fmt.Sprintf(" %d", value.(int))
+	}
+}
+
+func (m ControlMode) helpView() string {
+	return "\n" + m.help.ShortHelpView([]key.Binding{
+		m.keymap.up,
+		m.keymap.down,
+		m.keymap.decrease,
+		m.keymap.increase,
+		m.keymap.toggle,
+		m.keymap.refresh,
+		m.keymap.quit,
+	})
+}
+
+// value reads the control's current value from the device settings, as a bool for toggles and an int otherwise.
+func (c deviceControl) value(settings map[string]any) (any, bool) {
+	raw, ok := settings[c.setting]
+	if !ok {
+		return nil, false
+	}
+
+	if c.channel >= 0 {
+		channels, ok := raw.([]any)
+		if !ok || c.channel >= len(channels) {
+			return nil, false
+		}
+		raw = channels[c.channel]
+	}
+
+	switch v := raw.(type) {
+	case bool:
+		if c.kind != controlToggle {
+			return nil, false
+		}
+		return v, true
+	case float64:
+		if c.kind == controlToggle {
+			return v != 0, true
+		}
+		if c.kind == controlChoice && (int(v) < 0 || int(v) >= len(c.choices)) {
+			return 0, true
+		}
+		return int(v), true
+	}
+
+	return nil, false
+}
+
+// update returns the settings payload that sets the control to value.
+// Numbers are stored as float64 to match what decoding the settings JSON produces.
+func (c deviceControl) update(settings map[string]any, value any) map[string]any {
+	if number, ok := value.(int); ok {
+		value = float64(number)
+	}
+
+	if c.channel < 0 {
+		return map[string]any{c.setting: value}
+	}
+
+	channels := append([]any(nil), settings[c.setting].([]any)...)
+	channels[c.channel] = value
+
+	return map[string]any{c.setting: channels}
+}
+
+type controlFetchMsg struct {
+	settings map[string]any
+	stats    *DeviceStats
+	err      error
+}
+
+type controlSavedMsg struct {
+	label  string
+	update map[string]any
+	stats  *DeviceStats
+	err    error
+}
+
+func (m ControlMode) fetchDevice() tea.Msg {
+	settings, err := GetDeviceSettings()
+	if err != nil {
+		return controlFetchMsg{err: err}
+	}
+
+	stats, err := GetDeviceStats()
+	if err != nil {
+		return controlFetchMsg{err: err}
+	}
+
+	return controlFetchMsg{settings: settings, stats: stats}
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// loadControlMode returns a control panel that has read the clock's settings, with the brightness control selected.
+func loadControlMode(t *testing.T) ControlMode {
+	t.Helper()
+
+	m := NewControlMode()
+	model, _ := update(m, m.fetchDevice(), keyPress("down"))
+	m = model.(ControlMode)
+	if m.settings == nil {
+		t.Fatalf("expected the settings to be read, got %s", m.err)
+	}
+
+	return m
+}
+
+func TestControlModeTakesBackFailedSaves(t *testing.T) {
+	clock := newTestClock(t)
+	m := loadControlMode(t)
+
+	// The clock goes away once the settings have been read
+	server := httptest.NewServer(NewSimulator())
+	server.Close()
+	Host = server.URL
+
+	// A second step is built on the first before either has failed
+	model, first := m.Update(keyPress("right"))
+	model, second := model.Update(keyPress("right"))
+	if view := model.View(); !strings.Contains(view, " 116") {
+		t.Errorf("expected the new brightness to be shown while it's saved, got:\n%s", view)
+	}
+
+	model, _ = update(model, append(runCmd(first), runCmd(second)...)...)
+	m = model.(ControlMode)
+
+	if m.settings["BRI"] != float64(100) || !strings.Contains(m.View(), "Failed to save Brightness") {
+		t.Errorf("expected the brightness to go back to 100 after failing to save, got %v:\n%s", m.settings["BRI"], m.View())
+	}
+
+	if clock.Settings()["BRI"] != float64(100) {
+		t.Errorf("expected the clock to be left alone, got %v", clock.Settings()["BRI"])
+	}
+}
+
+func TestControlModeSavesSettings(t *testing.T) {
+	clock := newTestClock(t)
+	m := loadControlMode(t)
+
+	// adjust runs the update and its save, then returns the model
+	adjust := func(keys ...string) ControlMode {
+		t.Helper()
+
+		var model tea.Model = m
+		for _, key := range keys {
+			var cmd tea.Cmd
+			model, cmd = model.Update(keyPress(key))
+			model, _ = update(model, runCmd(cmd)...)
+		}
+
+		return model.(ControlMode)
+	}
+
+	m = adjust("right", "right", "left")
+	if clock.Settings()["BRI"] != float64(108) || !strings.Contains(m.View(), "Saved Brightness") {
+		t.Errorf("expected the brightness to be saved as 108, got %v:\n%s", clock.Settings()["BRI"], m.View())
+	}
+
+	// Sliders stop at the ends of their range
+	for i := 0; i < 40; i++ {
+		m = adjust("right")
+	}
+	if clock.Settings()["BRI"] != float64(255) {
+		t.Errorf("expected the brightness to stop at 255, got %v", clock.Settings()["BRI"])
+	}
+
+	m = adjust("up", " ")
+	if clock.Settings()["MATP"] != false || !strings.Contains(m.View(), "Saved Power") {
+		t.Errorf("expected space to turn the power off, got %v", clock.Settings()["MATP"])
+	}
+
+	// Only the selected channel of an RGB setting changes
+	m = adjust("down", "down", "down", "down", "left")
+	expected := []any{float64(255), float64(247), float64(255)}
+	if correction := clock.Settings()["CCORRECT"]; !reflect.DeepEqual(correction, expected) {
+		t.Errorf("expected green colour correction to be turned down, got %v", correction)
+	}
+
+	m = adjust("down", "down", "down", "down", "down", "down", "left", "left")
+	if clock.Settings()["TEFF"] != float64(len(transitionEffects)-1) || !strings.Contains(m.View(), "< Fade >") {
+		t.Errorf("expected the transition effect to go round to the last one, got %v", clock.Settings()["TEFF"])
+	}
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type DeviceInfo struct {
+	Width  int
+	Height int
+}
+
+// Device is the matrix size of the connected clock. It's probed when connecting, and stays at the stock awtrix size if that fails.
+var Device = DeviceInfo{Width: DefaultFrameWidth, Height: DefaultFrameHeight}
+
+// DeviceSizeOverride is used instead of probing when it's set, for devices that report their size wrongly or not at all.
+var DeviceSizeOverride DeviceInfo
+
+// DetectDevice returns DeviceSizeOverride if it's set, otherwise the probed size of the clock at host.
+// If probing fails the stock awtrix size is returned along with the error.
+func DetectDevice(host string) (DeviceInfo, error) {
+	if DeviceSizeOverride.Width > 0 && DeviceSizeOverride.Height > 0 {
+		return DeviceSizeOverride, nil
+	}
+
+	info, err := ProbeDevice(host)
+	if err != nil {
+		return DeviceInfo{Width: DefaultFrameWidth, Height: DefaultFrameHeight}, err
+	}
+
+	return info, nil
+}
+
+func (d DeviceInfo) String() string {
+	return fmt.Sprintf("%dx%d", d.Width, d.Height)
+}
+
+// ParseDeviceSize parses a size given as WxH, like 32x8.
+func ParseDeviceSize(size string) (DeviceInfo, error) {
+	width, height, ok := strings.Cut(strings.ToLower(size), "x")
+	if !ok {
+		return DeviceInfo{}, fmt.Errorf("invalid size %q, expected WxH", size)
+	}
+
+	w, err := strconv.Atoi(width)
+	if err != nil || w < 1 {
+		return DeviceInfo{}, fmt.Errorf("invalid width in size %q", size)
+	}
+
+	h, err := strconv.Atoi(height)
+	if err != nil || h < 1 {
+		return DeviceInfo{}, fmt.Errorf("invalid height in size %q", size)
+	}
+
+	return DeviceInfo{Width: w, Height: h}, nil
+}
+
+// ProbeDevice works out the matrix size of the clock at host. WLED reports its matrix size in /json/info,
+// awtrix doesn't, so the size is inferred from the number of pixels /api/screen returns.
+// UDP outputs like ddp:// are probed through the WLED JSON API of the same host.
+func ProbeDevice(host string) (DeviceInfo, error) {
+	hostURL, err := url.Parse(host)
+	if err != nil {
+		return DeviceInfo{}, err
+	}
+
+	if hostURL.Scheme != "http" && hostURL.Scheme != "https" {
+		return probeWLED("http://" + hostURL.Hostname())
+	}
+
+	info, err := probeWLED(host)
+	if err == nil {
+		return info, nil
+	}
+
+	var colors []uint32
+	err = hostGetJSON(host, "/api/screen", &colors)
+	if err != nil {
+		return DeviceInfo{}, err
+	}
+
+	// awtrix builds only vary the matrix width
+	if len(colors) == 0 || len(colors)%DefaultFrameHeight != 0 {
+		return DeviceInfo{}, fmt.Errorf("can't infer the matrix size from %d pixels", len(colors))
+	}
+
+	return DeviceInfo{Width: len(colors) / DefaultFrameHeight, Height: DefaultFrameHeight}, nil
+}
+
+func probeWLED(host string) (DeviceInfo, error) {
+	var info struct {
+		Leds struct {
+			Matrix struct {
+				W int `json:"w"`
+				H int `json:"h"`
+			} `json:"matrix"`
+		} `json:"leds"`
+	}
+
+	err := hostGetJSON(host, "/json/info", &info)
+	if err != nil {
+		return DeviceInfo{}, err
+	}
+
+	if info.Leds.Matrix.W < 1 || info.Leds.Matrix.H < 1 {
+		return DeviceInfo{}, errors.New("WLED device isn't set up as a 2D matrix")
+	}
+
+	return DeviceInfo{Width: info.Leds.Matrix.W, Height: info.Leds.Matrix.H}, nil
+}
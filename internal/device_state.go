@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"errors"
+	"sync"
+)
+
+// Settings that are restored once playback ends. Anything else in /api/settings is left alone.
+var restoredSettingKeys = []string{"BRI", "ABRI", "MATP", "ATRANS", "TEFF", "TSPEED", "ATIME", "CCORRECT", "CTEMP"}
+
+type DeviceState struct {
+	Settings map[string]any
+	App      string
+}
+
+func GetDeviceState() (*DeviceState, error) {
+	settings, err := GetDeviceSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := GetDeviceStats()
+	if err != nil {
+		return nil, err
+	}
+
+	state := &DeviceState{
+		Settings: make(map[string]any),
+		App:      stats.App,
+	}
+
+	for _, key := range restoredSettingKeys {
+		if value, ok := settings[key]; ok {
+			state.Settings[key] = value
+		}
+	}
+
+	return state, nil
+}
+
+func (ds *DeviceState) Restore() error {
+	// Always attempt every step so a single failure doesn't leave the clock stuck on the stream
+	var errs []error
+
+	errs = append(errs, DismissNotification(Host))
+
+	if len(ds.Settings) > 0 {
+		errs = append(errs, SetDeviceSettings(ds.Settings))
+	}
+
+	if ds.App != "" {
+		errs = append(errs, SwitchApp(ds.App))
+	}
+
+	return errors.Join(errs...)
+}
+
+var (
+	savedDeviceState      *DeviceState
+	savedDeviceStateMutex sync.Mutex
+)
+
+// SaveDeviceState snapshots the clock state unless a snapshot is already waiting to be restored.
+// Without a clock, when only playing to an output, there's nothing to save or restore.
+func SaveDeviceState() error {
+	savedDeviceStateMutex.Lock()
+	defer savedDeviceStateMutex.Unlock()
+
+	if savedDeviceState != nil || Host == "" {
+		return nil
+	}
+
+	state, err := GetDeviceState()
+	if err != nil {
+		// Keep an empty snapshot so the stream still gets dismissed on restore
+		savedDeviceState = &DeviceState{}
+		return err
+	}
+
+	savedDeviceState = state
+	return nil
+}
+
+// RestoreDeviceState restores and clears the snapshot taken by SaveDeviceState, if there is one.
+func RestoreDeviceState() error {
+	savedDeviceStateMutex.Lock()
+	defer savedDeviceStateMutex.Unlock()
+
+	if savedDeviceState == nil {
+		return nil
+	}
+
+	err := savedDeviceState.Restore()
+	savedDeviceState = nil
+	return err
+}
@@ -0,0 +1,336 @@
+package internal
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const MDNSAddress = "224.0.0.251:5353"
+const awtrixService = "_awtrix._tcp.local."
+
+const scanProbeTimeout = time.Millisecond * 800
+const scanConcurrency = 64
+
+// ErrPartialScan is returned along with the clocks found when a scan runs out of time before every address is probed.
+var ErrPartialScan = errors.New("the scan ran out of time")
+
+type DiscoveredClock struct {
+	Name string
+	Host string
+}
+
+// DiscoverMDNS asks for awtrix clocks over DNS-SD on the given multicast address until ctx is done.
+// The query is sent from an ephemeral port so responders answer with unicast legacy responses.
+func DiscoverMDNS(ctx context.Context, address string) ([]DiscoveredClock, error) {
+	addr, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	_, err = conn.WriteToUDP(encodeDNSQuery(awtrixService, dnsTypePTR), addr)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(time.Second * 2)
+	}
+	conn.SetReadDeadline(deadline)
+
+	// Records can be split across several responses, so they are collected before being resolved into clocks
+	records := make(map[string][]dnsRecord)
+	buf := make([]byte, 9000)
+
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			return nil, err
+		}
+
+		answers, err := decodeDNSResponse(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		for _, record := range answers {
+			records[strings.ToLower(record.name)] = append(records[strings.ToLower(record.name)], record)
+		}
+	}
+
+	var clocks []DiscoveredClock
+
+	for _, ptr := range records[awtrixService] {
+		if ptr.rtype != dnsTypePTR {
+			continue
+		}
+
+		for _, srv := range records[strings.ToLower(ptr.target)] {
+			if srv.rtype != dnsTypeSRV {
+				continue
+			}
+
+			for _, a := range records[strings.ToLower(srv.target)] {
+				if a.rtype != dnsTypeA {
+					continue
+				}
+
+				host := "http://" + a.ip.String()
+				if srv.port != 80 {
+					host += fmt.Sprintf(":%d", srv.port)
+				}
+
+				clocks = append(clocks, DiscoveredClock{
+					Name: strings.TrimSuffix(ptr.target, "."+awtrixService),
+					Host: host,
+				})
+			}
+		}
+	}
+
+	return dedupeClocks(clocks), nil
+}
+
+// ScanSubnets probes /api/stats on every address of the local IPv4 networks. Networks larger than a /24 only
+// have the /24 around the local address scanned.
+// If ctx runs out before every address is probed, the clocks found are returned along with ErrPartialScan.
+func ScanSubnets(ctx context.Context) ([]DiscoveredClock, error) {
+	ips, err := localScanAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	clocks, unprobed := ProbeClocks(ctx, ips)
+	if unprobed > 0 {
+		return clocks, fmt.Errorf("%w, %d of %d addresses weren't probed", ErrPartialScan, unprobed, len(ips))
+	}
+
+	return clocks, nil
+}
+
+// ScanTimeout returns how long ScanSubnets takes at most, since only scanConcurrency addresses are probed at a time.
+func ScanTimeout() time.Duration {
+	ips, _ := localScanAddresses()
+	rounds := (len(ips) + scanConcurrency - 1) / scanConcurrency
+
+	// One more probe's worth leaves time for the last round's stragglers
+	return max(DefaultDiscoverTimeout, time.Duration(rounds+1)*scanProbeTimeout)
+}
+
+func localScanAddresses() ([]net.IP, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() == nil || ipNet.IP.IsLoopback() {
+			continue
+		}
+
+		ips = append(ips, subnetHosts(ipNet)...)
+	}
+
+	return ips, nil
+}
+
+// ProbeClocks checks which of the given addresses answer /api/stats like an awtrix clock.
+// It also returns how many addresses ctx ran out before they could be probed.
+func ProbeClocks(ctx context.Context, ips []net.IP) ([]DiscoveredClock, int) {
+	var hosts []string
+	for _, ip := range ips {
+		hosts = append(hosts, "http://"+ip.String())
+	}
+
+	return probeHosts(ctx, hosts)
+}
+
+func probeHosts(ctx context.Context, hosts []string) ([]DiscoveredClock, int) {
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	var clocks []DiscoveredClock
+	var unprobed int
+
+	sem := make(chan struct{}, scanConcurrency)
+
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				mutex.Lock()
+				unprobed++
+				mutex.Unlock()
+				return
+			}
+			defer func() { <-sem }()
+
+			stats, err := probeClock(ctx, host)
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			switch {
+			// Cut short by ctx rather than the probe's own timeout
+			case err != nil && ctx.Err() != nil:
+				unprobed++
+			case err == nil:
+				clocks = append(clocks, DiscoveredClock{Name: stats.UID, Host: host})
+			}
+		}(host)
+	}
+
+	wg.Wait()
+
+	return dedupeClocks(clocks), unprobed
+}
+
+// probeClock asks host for its stats, with the credentials from the environment for clocks that have auth turned on.
+func probeClock(ctx context.Context, host string) (*DeviceStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, scanProbeTimeout)
+	defer cancel()
+
+	authHost, err := WithEnvCredentials(host)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, authHost+"/api/stats", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	var stats DeviceStats
+	err = json.NewDecoder(resp.Body).Decode(&stats)
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.Version == "" {
+		return nil, errors.New("not an awtrix clock")
+	}
+
+	return &stats, nil
+}
+
+// Discover finds clocks over mDNS, and also by scanning the local networks if scan is set.
+// A scan that ran out of time returns ErrPartialScan along with the clocks that were found.
+func Discover(ctx context.Context, scan bool) ([]DiscoveredClock, error) {
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	var clocks []DiscoveredClock
+	var errs []error
+
+	search := func(find func() ([]DiscoveredClock, error)) {
+		defer wg.Done()
+		found, err := find()
+		mutex.Lock()
+		clocks = append(clocks, found...)
+		errs = append(errs, err)
+		mutex.Unlock()
+	}
+
+	wg.Add(1)
+	go search(func() ([]DiscoveredClock, error) {
+		return DiscoverMDNS(ctx, MDNSAddress)
+	})
+
+	if scan {
+		wg.Add(1)
+		go search(func() ([]DiscoveredClock, error) {
+			return ScanSubnets(ctx)
+		})
+	}
+
+	wg.Wait()
+
+	clocks = dedupeClocks(clocks)
+	if len(clocks) > 0 {
+		// The clocks found are still returned, along with any scan that was cut short
+		var partial error
+		for _, err := range errs {
+			if errors.Is(err, ErrPartialScan) {
+				partial = err
+			}
+		}
+		return clocks, partial
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+func subnetHosts(ipNet *net.IPNet) []net.IP {
+	ip := ipNet.IP.To4()
+	mask := ipNet.Mask
+	if len(mask) == net.IPv6len {
+		mask = mask[12:]
+	}
+	if ones, _ := mask.Size(); ones < 24 {
+		mask = net.CIDRMask(24, 32)
+	}
+
+	network := ip.Mask(mask)
+	size := binary.BigEndian.Uint32(net.IP(mask).To4()) ^ 0xFFFFFFFF
+	start := binary.BigEndian.Uint32(network)
+
+	var ips []net.IP
+	// Skip the network and broadcast addresses
+	for i := uint32(1); i < size; i++ {
+		host := make(net.IP, 4)
+		binary.BigEndian.PutUint32(host, start+i)
+		if !host.Equal(ip) {
+			ips = append(ips, host)
+		}
+	}
+
+	return ips
+}
+
+func dedupeClocks(clocks []DiscoveredClock) []DiscoveredClock {
+	seen := make(map[string]bool)
+	var unique []DiscoveredClock
+
+	for _, clock := range clocks {
+		if !seen[clock.Host] {
+			seen[clock.Host] = true
+			unique = append(unique, clock)
+		}
+	}
+
+	sort.Slice(unique, func(i, j int) bool {
+		return unique[i].Host < unique[j].Host
+	})
+
+	return unique
+}
@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const DefaultDiscoverTimeout = time.Second * 3
+
+type DiscoverMode struct {
+	spinner   spinner.Model
+	searching bool
+	scanned   bool
+	clocks    []DiscoveredClock
+	selected  int
+	err       error
+}
+
+func NewDiscoverMode() DiscoverMode {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+
+	return DiscoverMode{
+		spinner:   s,
+		searching: true,
+	}
+}
+
+type discoverMsg struct {
+	clocks []DiscoveredClock
+	err    error
+}
+
+func (m DiscoverMode) Init() tea.Cmd {
+	return tea.Batch(m.spinner.Tick, discover(false))
+}
+
+func (m DiscoverMode) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q":
+			return m, tea.Quit
+
+		case "up", "k":
+			if m.selected > 0 {
+				m.selected--
+			}
+
+		case "down", "j":
+			if m.selected < len(m.clocks)-1 {
+				m.selected++
+			}
+
+		case "enter":
+			if m.selected >= len(m.clocks) {
+				return m, nil
+			}
+
+			host, err := WithEnvCredentials(m.clocks[m.selected].Host)
+			if err != nil {
+				m.err = err
+				return m, nil
+			}
+
+			return m, connectClock(host)
+
+		case "r", "s":
+			if m.searching {
+				return m, nil
+			}
+
+			m.searching = true
+			m.scanned = msg.String() == "s"
+			return m, tea.Batch(m.spinner.Tick, discover(m.scanned))
+		}
+
+	case discoverMsg:
+		m.searching = false
+		m.clocks = msg.clocks
+		m.err = msg.err
+		m.selected = min(m.selected, max(len(m.clocks)-1, 0))
+		return m, nil
+
+	case connectMsg:
+		Host = msg.host
+		Device = msg.device
+		return SwitchMode(NewMenuMode())
+
+	case spinner.TickMsg:
+		if m.searching {
+			var cmd tea.Cmd
+			m.spinner, cmd = m.spinner.Update(msg)
+			return m, cmd
+		}
+	}
+
+	return m, nil
+}
+
+func (m DiscoverMode) View() string {
+	var s strings.Builder
+
+	s.WriteString("pixelstream - Stream videos to your awtrix clock with ease.\n\n")
+
+	switch {
+	case m.searching:
+		s.WriteString(m.spinner.View())
+		if m.scanned {
+			s.WriteString("Searching for clocks and scanning the local network...\n")
+		} else {
+			s.WriteString("Searching for clocks...\n")
+		}
+	case len(m.clocks) == 0:
+		s.WriteString("No clocks found. Press s to scan the local network, or pass the host as an argument.\n")
+		if m.err != nil {
+			s.WriteString(helpStyle(m.err.Error()))
+			s.WriteRune('\n')
+		}
+	default:
+		s.WriteString("Select a clock:\n\n")
+		for i, clock := range m.clocks {
+			label := clock.Host
+			if clock.Name != "" {
+				label = clock.Name + " (" + clock.Host + ")"
+			}
+
+			if i == m.selected {
+				s.WriteString(selectedItemStyle.Render("> " + label))
+			} else {
+				s.WriteString(itemStyle.Render(label))
+			}
+			s.WriteRune('\n')
+		}
+
+		// Such as a scan that ran out of time
+		if m.err != nil {
+			s.WriteString(helpStyle(m.err.Error()))
+			s.WriteRune('\n')
+		}
+	}
+
+	s.WriteRune('\n')
+	s.WriteString(helpStyle("[q] quit  [↑/↓] select  [enter] connect  [r] search again  [s] scan network\n"))
+
+	return s.String()
+}
+
+func discover(scan bool) tea.Cmd {
+	return func() tea.Msg {
+		timeout := DefaultDiscoverTimeout
+		if scan {
+			timeout = ScanTimeout()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		clocks, err := Discover(ctx, scan)
+		return discoverMsg{clocks: clocks, err: err}
+	}
+}
+
+type connectMsg struct {
+	host   string
+	device DeviceInfo
+}
+
+// connectClock probes the matrix size of the selected clock before connecting to it.
+// A clock that can't be probed is assumed to be a stock awtrix.
+func connectClock(host string) tea.Cmd {
+	return func() tea.Msg {
+		device, _ := DetectDevice(host)
+		return connectMsg{host: host, device: device}
+	}
+}
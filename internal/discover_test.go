@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func appendDNSRecord(msg []byte, name string, rtype uint16, data []byte) []byte {
+	msg = appendDNSName(msg, name)
+	msg = binary.BigEndian.AppendUint16(msg, rtype)
+	msg = binary.BigEndian.AppendUint16(msg, dnsClassIN)
+	msg = binary.BigEndian.AppendUint32(msg, 120)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(data)))
+	return append(msg, data...)
+}
+
+// startMDNSResponder answers DNS-SD queries for awtrix clocks like a clock named "living-room" at 192.168.1.170:80 would.
+// The answer is split across two responses and the SRV target uses name compression, as real responders do.
+func startMDNSResponder(t *testing.T) string {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		name, err := decodeDNSQuestion(buf[:n])
+		if err != nil || name != awtrixService {
+			return
+		}
+
+		instance := "living-room." + awtrixService
+
+		header := make([]byte, 12)
+		binary.BigEndian.PutUint16(header[2:], 0x8400)
+
+		first := append([]byte(nil), header...)
+		binary.BigEndian.PutUint16(first[6:], 1)
+		first = appendDNSRecord(first, awtrixService, dnsTypePTR, appendDNSName(nil, instance))
+		conn.WriteToUDP(first, addr)
+
+		second := append([]byte(nil), header...)
+		binary.BigEndian.PutUint16(second[6:], 1)
+		binary.BigEndian.PutUint16(second[10:], 1)
+		hostOffset := len(second)
+		second = appendDNSRecord(second, "awtrix-living.local.", dnsTypeA, []byte{192, 168, 1, 170})
+		srv := []byte{0, 0, 0, 0, 0, 80, 0xC0 | byte(hostOffset>>8), byte(hostOffset)}
+		second = appendDNSRecord(second, instance, dnsTypeSRV, srv)
+		conn.WriteToUDP(second, addr)
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+// decodeDNSQuestion returns the name asked about by a single PTR question query.
+func decodeDNSQuestion(msg []byte) (string, error) {
+	if len(msg) < 12 || binary.BigEndian.Uint16(msg[4:]) != 1 {
+		return "", errDNSTruncated
+	}
+
+	name, next, err := readDNSName(msg, 12)
+	if err != nil {
+		return "", err
+	}
+
+	if next+4 > len(msg) || binary.BigEndian.Uint16(msg[next:]) != dnsTypePTR {
+		return "", errDNSTruncated
+	}
+
+	return name, nil
+}
+
+func TestDiscoverMDNS(t *testing.T) {
+	address := startMDNSResponder(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*300)
+	defer cancel()
+
+	clocks, err := DiscoverMDNS(ctx, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(clocks) != 1 {
+		t.Fatalf("expected 1 clock, got %v", clocks)
+	}
+
+	if clocks[0].Name != "living-room" || clocks[0].Host != "http://192.168.1.170" {
+		t.Errorf("expected living-room at http://192.168.1.170, got %s at %s", clocks[0].Name, clocks[0].Host)
+	}
+}
+
+func TestReadDNSNameRejectsPointerLoops(t *testing.T) {
+	msg := []byte{0xC0, 0x00}
+
+	_, _, err := readDNSName(msg, 0)
+	if err == nil {
+		t.Error("expected a compression pointer loop to be rejected")
+	}
+}
+
+func TestSubnetHosts(t *testing.T) {
+	_, ipNet, err := net.ParseCIDR("192.168.1.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipNet.IP = net.IPv4(192, 168, 1, 20).To4()
+
+	hosts := subnetHosts(ipNet)
+	if len(hosts) != 253 {
+		t.Errorf("expected 253 hosts, skipping the network, broadcast and local addresses, got %d", len(hosts))
+	}
+
+	if !hosts[0].Equal(net.IPv4(192, 168, 1, 1)) || !hosts[len(hosts)-1].Equal(net.IPv4(192, 168, 1, 254)) {
+		t.Errorf("expected hosts from 192.168.1.1 to 192.168.1.254, got %s to %s", hosts[0], hosts[len(hosts)-1])
+	}
+
+	_, ipNet, err = net.ParseCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipNet.IP = net.IPv4(10, 1, 2, 3).To4()
+
+	hosts = subnetHosts(ipNet)
+	if len(hosts) != 253 || !hosts[0].Equal(net.IPv4(10, 1, 2, 1)) {
+		t.Errorf("expected large networks to only scan the local /24, got %d hosts starting at %s", len(hosts), hosts[0])
+	}
+}
+
+func TestProbeHostsWithCredentials(t *testing.T) {
+	server := httptest.NewServer(requireAuth(NewSimulator()))
+	defer server.Close()
+
+	clocks, unprobed := probeHosts(context.Background(), []string{server.URL})
+	if len(clocks) != 0 || unprobed != 0 {
+		t.Errorf("expected a clock with auth not to be found without credentials, got %v", clocks)
+	}
+
+	t.Setenv("PIXELSTREAM_USERNAME", "admin")
+	t.Setenv("PIXELSTREAM_PASSWORD", "secret")
+	clocks, _ = probeHosts(context.Background(), []string{server.URL})
+	if len(clocks) != 1 || clocks[0].Host != server.URL {
+		t.Errorf("expected the clock to be found with the credentials from the environment, and listed without them, got %v", clocks)
+	}
+}
+
+func TestProbeHostsReportsPartialScans(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	hosts := make([]string, scanConcurrency*2)
+	for i := range hosts {
+		hosts[i] = "http://127.0.0.1:1"
+	}
+
+	if _, unprobed := probeHosts(ctx, hosts); unprobed != len(hosts) {
+		t.Errorf("expected all %d addresses to be reported as unprobed, got %d", len(hosts), unprobed)
+	}
+
+	if timeout := ScanTimeout(); timeout < DefaultDiscoverTimeout {
+		t.Errorf("expected the scan timeout to be at least the default, got %s", timeout)
+	}
+}
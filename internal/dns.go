@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+)
+
+// Just enough of the DNS wire format (RFC 1035) for DNS-SD lookups over mDNS.
+
+const (
+	dnsTypeA   uint16 = 1
+	dnsTypePTR uint16 = 12
+	dnsTypeSRV uint16 = 33
+)
+
+const dnsClassIN uint16 = 1
+
+var errDNSTruncated = errors.New("truncated DNS message")
+
+type dnsRecord struct {
+	name  string
+	rtype uint16
+	// Set for PTR and SRV records
+	target string
+	// Set for SRV records
+	port uint16
+	// Set for A records
+	ip net.IP
+}
+
+func encodeDNSQuery(name string, qtype uint16) []byte {
+	msg := make([]byte, 12)
+	// A single question, every other header field is zero for mDNS queries
+	binary.BigEndian.PutUint16(msg[4:], 1)
+
+	msg = appendDNSName(msg, name)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, dnsClassIN)
+
+	return msg
+}
+
+func appendDNSName(msg []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+
+	return append(msg, 0)
+}
+
+// decodeDNSResponse returns the answer, authority and additional records of msg that are A, PTR or SRV records.
+func decodeDNSResponse(msg []byte) ([]dnsRecord, error) {
+	if len(msg) < 12 {
+		return nil, errDNSTruncated
+	}
+
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	recordCount := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) + int(binary.BigEndian.Uint16(msg[10:]))
+	offset := 12
+
+	for i := 0; i < questions; i++ {
+		_, next, err := readDNSName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		offset = next + 4
+	}
+
+	var records []dnsRecord
+
+	for i := 0; i < recordCount; i++ {
+		name, next, err := readDNSName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		if next+10 > len(msg) {
+			return nil, errDNSTruncated
+		}
+
+		record := dnsRecord{
+			name:  name,
+			rtype: binary.BigEndian.Uint16(msg[next:]),
+		}
+		dataLength := int(binary.BigEndian.Uint16(msg[next+8:]))
+		data := next + 10
+		offset = data + dataLength
+
+		if offset > len(msg) {
+			return nil, errDNSTruncated
+		}
+
+		switch record.rtype {
+		case dnsTypeA:
+			if dataLength != 4 {
+				continue
+			}
+			record.ip = net.IP(append([]byte(nil), msg[data:data+4]...))
+
+		case dnsTypePTR:
+			record.target, _, err = readDNSName(msg, data)
+			if err != nil {
+				return nil, err
+			}
+
+		case dnsTypeSRV:
+			if dataLength < 7 {
+				continue
+			}
+			record.port = binary.BigEndian.Uint16(msg[data+4:])
+			record.target, _, err = readDNSName(msg, data+6)
+			if err != nil {
+				return nil, err
+			}
+
+		default:
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// readDNSName reads a possibly compressed name at offset, returning it and the offset just past it.
+func readDNSName(msg []byte, offset int) (string, int, error) {
+	var labels []string
+	end := -1
+
+	// Bounded so a pointer loop in a malformed message can't hang
+	for jumps := 0; jumps < 64; {
+		if offset >= len(msg) {
+			return "", 0, errDNSTruncated
+		}
+
+		length := int(msg[offset])
+
+		switch {
+		case length == 0:
+			if end < 0 {
+				end = offset + 1
+			}
+			return strings.Join(labels, ".") + ".", end, nil
+
+		case length&0xC0 == 0xC0:
+			if offset+1 >= len(msg) {
+				return "", 0, errDNSTruncated
+			}
+			if end < 0 {
+				end = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(msg[offset:]) & 0x3FFF)
+			jumps++
+
+		default:
+			if offset+1+length > len(msg) {
+				return "", 0, errDNSTruncated
+			}
+			labels = append(labels, string(msg[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+
+	return "", 0, errors.New("too many DNS name compression pointers")
+}
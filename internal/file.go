@@ -2,6 +2,7 @@ package internal
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -10,25 +11,35 @@ import (
 
 const pixelstreamFileExt = ".pxlstrm"
 const pixelstreamFormatIdentifier = "PXLSTRM"
-const pixelstreamFormatVersion uint8 = 1
 
-const frameSize = frameArea * 3
+// Version 2 added the frame width and height to the header, version 1 files are always 32x8
+const pixelstreamFormatVersion uint8 = 2
 
 func (ps *PixelStream) SaveFile(fl FileLocation) error {
-	buf := bytes.NewBuffer(make([]byte, 0, len(pixelstreamFormatIdentifier)+2+(len(ps.Frames)*frameSize)))
+	frameSize := ps.Width * ps.Height * 3
+	buf := bytes.NewBuffer(make([]byte, 0, len(pixelstreamFormatIdentifier)+6+(len(ps.Frames)*frameSize)))
 
 	_, err := buf.WriteString(pixelstreamFormatIdentifier)
 	if err != nil {
 		return err
 	}
 
-	_, err = buf.Write([]byte{ps.Version, ps.FrameRate})
+	_, err = buf.Write([]byte{pixelstreamFormatVersion, ps.FrameRate})
+	if err != nil {
+		return err
+	}
+
+	err = binary.Write(buf, binary.BigEndian, [2]uint16{uint16(ps.Width), uint16(ps.Height)})
 	if err != nil {
 		return err
 	}
 
 	for _, frame := range ps.Frames {
-		for _, pixel := range frame {
+		if frame.Width != ps.Width || frame.Height != ps.Height {
+			return fmt.Errorf("frame size %dx%d doesn't match the stream's %dx%d", frame.Width, frame.Height, ps.Width, ps.Height)
+		}
+
+		for _, pixel := range frame.Pixels {
 			_, err = buf.Write(pixel[:])
 			if err != nil {
 				return err
@@ -50,32 +61,50 @@ func LoadFile(fl FileLocation) (*PixelStream, error) {
 		return nil, err
 	}
 
-	if !strings.HasPrefix(pixelstreamFormatIdentifier, string(file[0:len(pixelstreamFormatIdentifier)])) {
+	if len(file) < len(pixelstreamFormatIdentifier)+2 || !strings.HasPrefix(pixelstreamFormatIdentifier, string(file[0:len(pixelstreamFormatIdentifier)])) {
 		return nil, errors.New("invalid/corrupt pxlstrm file")
 	}
 
 	fileVersion := file[len(pixelstreamFormatIdentifier)]
 
-	if fileVersion != pixelstreamFormatVersion {
-		return nil, fmt.Errorf("unsupported pxlstrm format version: found %d, expected %d", fileVersion, pixelstreamFormatVersion)
-	}
-
-	frameCount := (len(file) - (len(pixelstreamFormatIdentifier) + 2)) / frameSize
-
 	output := &PixelStream{
 		Version:   fileVersion,
 		FrameRate: file[len(pixelstreamFormatIdentifier)+1],
-		Frames:    make([]Frame, 0, frameCount),
+		Width:     DefaultFrameWidth,
+		Height:    DefaultFrameHeight,
 	}
 
-	for i := len(pixelstreamFormatIdentifier) + 2; i < len(file); i += frameSize {
-		var frame Frame
+	headerSize := len(pixelstreamFormatIdentifier) + 2
+
+	switch fileVersion {
+	case 1:
+	case 2:
+		if len(file) < headerSize+4 {
+			return nil, errors.New("invalid/corrupt pxlstrm file")
+		}
+		output.Width = int(binary.BigEndian.Uint16(file[headerSize:]))
+		output.Height = int(binary.BigEndian.Uint16(file[headerSize+2:]))
+		headerSize += 4
+	default:
+		return nil, fmt.Errorf("unsupported pxlstrm format version: found %d, expected %d or older", fileVersion, pixelstreamFormatVersion)
+	}
+
+	frameSize := output.Width * output.Height * 3
+	if frameSize == 0 {
+		return nil, errors.New("invalid/corrupt pxlstrm file")
+	}
+
+	frameCount := (len(file) - headerSize) / frameSize
+	output.Frames = make([]Frame, 0, frameCount)
+
+	for i := headerSize; i+frameSize <= len(file); i += frameSize {
+		frame := NewFrame(output.Width, output.Height)
 
 		for j := 0; j < frameSize; j += 3 {
-			frame[j/3] = [3]uint8{file[i+j], file[i+j+1], file[i+j+2]}
+			frame.Pixels[j/3] = [3]uint8{file[i+j], file[i+j+1], file[i+j+2]}
 		}
 
-		output.Frames = append(output.Frames, frame)
+		output.Frames = append(output.Frames, *frame)
 	}
 
 	return output, nil
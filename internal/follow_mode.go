@@ -0,0 +1,303 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// mpv reports its position with every frame it shows. The player keeps time by itself between reports,
+// so it's only moved when it has drifted further than this, since seeking resends the frame.
+const followTolerance = time.Millisecond * 150
+
+// FollowMode mirrors an mpv player on the clock, loading or converting whatever file mpv has open
+// and keeping to its position and pause state.
+type FollowMode struct {
+	socket  string
+	client  *MPVClient
+	state   MPVState
+	err     error
+	spinner spinner.Model
+	// The file being loaded or played, which lags behind state.Path while it's converted
+	path     string
+	loading  bool
+	player   *Player
+	frame    *Frame
+	position time.Duration
+	keymap   FollowModeKeymap
+	help     help.Model
+	progress progress.Model
+}
+
+type FollowModeKeymap struct {
+	quit key.Binding
+}
+
+func NewFollowMode(socket string) FollowMode {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+
+	return FollowMode{
+		socket:  socket,
+		spinner: s,
+		keymap: FollowModeKeymap{
+			quit: key.NewBinding(
+				key.WithKeys("ctrl+c", "q"),
+				key.WithHelp("q", "quit"),
+			),
+		},
+		help:     help.New(),
+		progress: progress.New(progress.WithoutPercentage(), progress.WithWidth(46), progress.WithScaledGradient("#FF7CCB", "#FDFF8C")),
+	}
+}
+
+func (m FollowMode) Init() tea.Cmd {
+	return tea.Batch(m.spinner.Tick, m.connect, m.tick())
+}
+
+type followConnectedMsg struct {
+	client *MPVClient
+	err    error
+}
+
+type followStateMsg MPVState
+
+type followDisconnectedMsg struct {
+	err error
+}
+
+type followLoadedMsg struct {
+	path        string
+	pixelstream *PixelStream
+	err         error
+}
+
+type followTickMsg struct{}
+
+func (m FollowMode) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Sequence(m.close, tea.Quit)
+
+		case "q":
+			return NewMenuMode(), tea.Sequence(m.close, restoreDeviceState)
+		}
+
+	case followConnectedMsg:
+		m.client, m.err = msg.client, msg.err
+		if m.err != nil {
+			return m, nil
+		}
+		return m, m.nextState
+
+	case followStateMsg:
+		m.state = MPVState(msg)
+		if m.state.Path == m.path {
+			m.follow()
+			return m, m.nextState
+		}
+
+		// mpv moved on to another file, or closed the one it had
+		var closeCmd tea.Cmd
+		if m.player != nil {
+			closeCmd = closePlayer(m.player)
+			m.player = nil
+		}
+		m.path = m.state.Path
+		m.err = nil
+		m.loading = m.path != ""
+
+		if !m.loading {
+			return m, tea.Batch(closeCmd, m.nextState)
+		}
+		return m, tea.Batch(closeCmd, m.nextState, m.load(m.path))
+
+	case followLoadedMsg:
+		// mpv has opened something else while this one was loading
+		if msg.path != m.path {
+			return m, nil
+		}
+
+		m.loading = false
+		m.err = msg.err
+		if m.err != nil {
+			return m, nil
+		}
+
+		m.player = NewPlayer(msg.pixelstream, output())
+		m.follow()
+		return m.observePlayer(), saveDeviceState
+
+	case followDisconnectedMsg:
+		// With mpv gone there's nothing to follow, so the clock is given back rather than played to the end of the file
+		m.err = msg.err
+		m.path = ""
+		m.loading = false
+
+		var closeCmd tea.Cmd
+		if m.player != nil {
+			m.player.Pause()
+			closeCmd = closePlayer(m.player)
+			m.player = nil
+		}
+		return m, tea.Sequence(closeCmd, restoreDeviceState)
+
+	case followTickMsg:
+		if m.player != nil {
+			m = m.observePlayer()
+		}
+		return m, m.tick()
+	}
+
+	var spinnerCmd tea.Cmd
+	m.spinner, spinnerCmd = m.spinner.Update(msg)
+	return m, spinnerCmd
+}
+
+func (m FollowMode) connect() tea.Msg {
+	client, err := DialMPV(m.socket)
+	return followConnectedMsg{client: client, err: err}
+}
+
+// nextState waits for mpv to do something.
+func (m FollowMode) nextState() tea.Msg {
+	state, ok := <-m.client.States()
+	if !ok {
+		return followDisconnectedMsg{err: m.client.Err()}
+	}
+
+	return followStateMsg(state)
+}
+
+// load converts path the first time it's seen, like playing it would, and fits it to the clock.
+// ffmpeg is kept quiet, since its output would be written over the view.
+func (m FollowMode) load(path string) tea.Cmd {
+	return func() tea.Msg {
+		pixelstream, err := loadOrGenerate(FromOSPath(path), Device.Width, Device.Height, true)
+		if err != nil {
+			return followLoadedMsg{path: path, err: err}
+		}
+
+		return followLoadedMsg{path: path, pixelstream: pixelstream.Resize(Device.Width, Device.Height)}
+	}
+}
+
+// follow brings the player in line with mpv.
+func (m FollowMode) follow() {
+	if m.player == nil {
+		return
+	}
+
+	if m.state.Paused {
+		m.player.Pause()
+	}
+
+	if drift := m.player.Position() - m.state.Position; drift > followTolerance || drift < -followTolerance {
+		m.player.Seek(m.state.Position)
+	}
+
+	if !m.state.Paused {
+		m.player.Play()
+	}
+}
+
+// The view is refreshed at the default frame rate, whether or not there's anything playing
+func (m FollowMode) tick() tea.Cmd {
+	return tea.Tick(time.Second/DefaultFrameRate, func(_ time.Time) tea.Msg {
+		return followTickMsg{}
+	})
+}
+
+func (m FollowMode) observePlayer() FollowMode {
+	m.position = m.player.Position()
+	m.frame = m.player.Frame()
+	return m
+}
+
+// Close stops the player and lets go of mpv, for when the program is quit without going through Update, such as by a signal.
+func (m FollowMode) Close() {
+	m.close()
+}
+
+func (m FollowMode) close() tea.Msg {
+	if m.player != nil {
+		m.player.Close()
+	}
+	if m.client != nil {
+		m.client.Close()
+	}
+	return nil
+}
+
+func closePlayer(player *Player) tea.Cmd {
+	return func() tea.Msg {
+		player.Close()
+		return nil
+	}
+}
+
+func (m FollowMode) View() string {
+	var s strings.Builder
+
+	switch {
+	case m.client == nil && m.err != nil:
+		s.WriteString("Couldn't connect to mpv at ")
+		s.WriteString(m.socket)
+		s.WriteString(": ")
+		s.WriteString(m.err.Error())
+		s.WriteString("\nStart mpv with --input-ipc-server=")
+		s.WriteString(m.socket)
+		s.WriteRune('\n')
+	case m.client == nil:
+		s.WriteString(m.spinner.View())
+		s.WriteString("Connecting to mpv at ")
+		s.WriteString(m.socket)
+		s.WriteRune('\n')
+	case m.err != nil:
+		s.WriteString("Error following mpv: ")
+		s.WriteString(m.err.Error())
+		s.WriteRune('\n')
+	case m.path == "":
+		s.WriteString(m.spinner.View())
+		s.WriteString("Waiting for mpv to open a file\n")
+	case m.loading:
+		s.WriteString(m.spinner.View())
+		s.WriteString("Loading ")
+		s.WriteString(filepath.Base(m.path))
+		s.WriteString(", converting it if it hasn't been played before\n")
+	case m.player != nil:
+		s.WriteString(m.frame.View())
+		s.WriteRune('\n')
+
+		s.WriteString(FmtDuration(m.position))
+		s.WriteRune(' ')
+		s.WriteString(m.progress.ViewAs(float64(m.position) / float64(m.player.Duration())))
+		s.WriteRune(' ')
+		s.WriteString(FmtDuration(m.player.Duration()))
+		s.WriteRune('\n')
+
+		status := "Playing"
+		if m.state.Paused {
+			status = "Paused"
+		}
+		s.WriteString(fmt.Sprintf("%s %s in mpv\n", status, filepath.Base(m.path)))
+	}
+
+	s.WriteString("\n")
+	s.WriteString(m.help.ShortHelpView([]key.Binding{
+		m.keymap.quit,
+	}))
+
+	return s.String()
+}
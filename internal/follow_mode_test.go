@@ -0,0 +1,245 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeMPV stands in for mpv's IPC socket, recording the commands it's sent.
+type fakeMPV struct {
+	socket   string
+	listener net.Listener
+	conn     net.Conn
+	commands []string
+}
+
+func newFakeMPV(t *testing.T) *fakeMPV {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "mpvsocket")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets aren't available: %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	return &fakeMPV{socket: socket, listener: listener}
+}
+
+// accept takes the connection of a client that has dialled the socket.
+func (mpv *fakeMPV) accept(t *testing.T) {
+	t.Helper()
+
+	conn, err := mpv.listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	mpv.conn = conn
+}
+
+// readCommands reads count commands from the client.
+func (mpv *fakeMPV) readCommands(t *testing.T, count int) {
+	t.Helper()
+
+	mpv.conn.SetReadDeadline(time.Now().Add(time.Second * 2))
+	reader := bufio.NewReader(mpv.conn)
+	for i := 0; i < count; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("expected %d commands, got %d: %s", count, i, err)
+		}
+		mpv.commands = append(mpv.commands, strings.TrimSpace(line))
+	}
+}
+
+func (mpv *fakeMPV) set(t *testing.T, name string, value any) {
+	t.Helper()
+
+	data, _ := json.Marshal(value)
+	_, err := fmt.Fprintf(mpv.conn, `{"event":"property-change","id":1,"name":%q,"data":%s}`+"\n", name, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitForState receives states from m's client until one matches, and feeds it to m.
+func waitForState(t *testing.T, m FollowMode, matches func(MPVState) bool) FollowMode {
+	t.Helper()
+
+	for {
+		select {
+		case state, ok := <-m.client.States():
+			if !ok {
+				t.Fatalf("expected mpv to stay connected, got %s", m.client.Err())
+			}
+
+			model, _ := m.Update(followStateMsg(state))
+			m = model.(FollowMode)
+			if matches(state) {
+				return m
+			}
+		case <-time.After(time.Second * 2):
+			t.Fatalf("expected mpv's state to change, last saw %+v", m.state)
+		}
+	}
+}
+
+func TestFollowModeFollowsMPV(t *testing.T) {
+	newTestClock(t)
+	mpv := newFakeMPV(t)
+
+	ps := newTestStream(160, 16)
+	fl := saveTestStream(t, ps)
+	dir, name := filepath.Split(fl.ToOSPath())
+
+	m := NewFollowMode(mpv.socket)
+	model, _ := m.Update(m.connect())
+	m = model.(FollowMode)
+	if m.client == nil {
+		t.Fatalf("expected to connect to mpv, got %s", m.err)
+	}
+	defer m.close()
+
+	mpv.accept(t)
+	mpv.readCommands(t, len(mpvProperties))
+	for i, name := range mpvProperties {
+		expected := fmt.Sprintf(`{"command":["observe_property",%d,%q]}`, i+1, name)
+		if mpv.commands[i] != expected {
+			t.Errorf("expected %s, got %s", expected, mpv.commands[i])
+		}
+	}
+
+	// A relative path is resolved against mpv's working directory
+	mpv.set(t, "working-directory", dir)
+	mpv.set(t, "path", name)
+	mpv.set(t, "pause", false)
+	mpv.set(t, "time-pos", 2.0)
+	m = waitForState(t, m, func(state MPVState) bool { return state.Position == time.Second*2 })
+
+	if m.path != fl.ToOSPath() || !m.loading {
+		t.Fatalf("expected %s to be loading, got %q", fl.ToOSPath(), m.path)
+	}
+
+	model, _ = m.Update(m.load(m.path)())
+	m = model.(FollowMode)
+	if m.player == nil {
+		t.Fatalf("expected the file to be played, got %s", m.err)
+	}
+
+	if position := m.player.Position(); !m.player.Playing() || position < time.Second*2 || position > time.Second*3 {
+		t.Errorf("expected playback to start at mpv's position of 2s, got %s", position)
+	}
+
+	if view := m.View(); !strings.Contains(view, "Playing "+name) {
+		t.Errorf("expected the view to show what mpv is playing, got:\n%s", view)
+	}
+
+	mpv.set(t, "pause", true)
+	mpv.set(t, "time-pos", 5.0)
+	m = waitForState(t, m, func(state MPVState) bool { return state.Paused && state.Position == time.Second*5 })
+
+	if m.player.Playing() || m.player.Position() != time.Second*5 {
+		t.Errorf("expected the player to pause at mpv's position of 5s, got %s", m.player.Position())
+	}
+
+	if frame := m.player.Frame(); frame.Pixels[0][0] != 80 {
+		t.Errorf("expected frame 80 at 5s, got %d", frame.Pixels[0][0])
+	}
+
+	// Small differences are left to the player, so it isn't seeking all the time
+	mpv.set(t, "time-pos", 5.1)
+	m = waitForState(t, m, func(state MPVState) bool { return state.Position != time.Second*5 })
+	if m.player.Position() != time.Second*5 {
+		t.Errorf("expected a 100ms difference to be ignored, got %s", m.player.Position())
+	}
+
+	// Closing the file stops playback
+	player := m.player
+	mpv.set(t, "path", nil)
+	m = waitForState(t, m, func(state MPVState) bool { return state.Path == "" })
+	if m.player != nil || m.loading {
+		t.Error("expected nothing to be played once mpv closes the file")
+	}
+	player.Close()
+
+	mpv.conn.Close()
+	model, _ = m.Update(m.nextState())
+	m = model.(FollowMode)
+	if view := m.View(); !strings.Contains(view, "mpv closed the connection") {
+		t.Errorf("expected the view to say mpv went away, got:\n%s", view)
+	}
+}
+
+func TestFollowModeLetsGoWhenMPVQuits(t *testing.T) {
+	clock := newTestClock(t)
+	mpv := newFakeMPV(t)
+
+	fl := saveTestStream(t, newTestStream(160, 16))
+
+	m := NewFollowMode(mpv.socket)
+	model, _ := m.Update(m.connect())
+	m = model.(FollowMode)
+	if m.client == nil {
+		t.Fatalf("expected to connect to mpv, got %s", m.err)
+	}
+	defer m.close()
+
+	mpv.accept(t)
+	mpv.readCommands(t, len(mpvProperties))
+	mpv.set(t, "path", fl.ToOSPath())
+	mpv.set(t, "pause", false)
+	m = waitForState(t, m, func(state MPVState) bool { return state.Path != "" && !state.Paused })
+
+	model, cmd := m.Update(m.load(m.path)())
+	m = model.(FollowMode)
+	runCmd(cmd)
+	if m.player == nil {
+		t.Fatalf("expected the file to be played, got %s", m.err)
+	}
+
+	err := SwitchApp("Date")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	player := m.player
+	mpv.conn.Close()
+
+	// States still on their way are skipped until the disconnection
+	msg := m.nextState()
+	for _, ok := msg.(followDisconnectedMsg); !ok; _, ok = msg.(followDisconnectedMsg) {
+		msg = m.nextState()
+	}
+	model, cmd = m.Update(msg)
+	runCmd(cmd)
+
+	if player.Playing() {
+		t.Error("expected playback to stop once mpv goes away")
+	}
+	if clock.NotificationActive() {
+		t.Error("expected the stream to be dismissed")
+	}
+	if clock.App() != "Time" {
+		t.Errorf("expected the Time app to be restored, got %s", clock.App())
+	}
+	if view := model.View(); !strings.Contains(view, "mpv closed the connection") {
+		t.Errorf("expected the view to say mpv went away, got:\n%s", view)
+	}
+}
+
+func TestFollowModeWithoutMPV(t *testing.T) {
+	m := NewFollowMode(filepath.Join(t.TempDir(), "missing"))
+	model, _ := m.Update(m.connect())
+
+	if view := model.View(); !strings.Contains(view, "Couldn't connect to mpv") || !strings.Contains(view, "--input-ipc-server=") {
+		t.Errorf("expected the view to explain how to start mpv, got:\n%s", view)
+	}
+}
@@ -5,66 +5,150 @@ import (
 	"image/color"
 	"io"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/muesli/termenv"
 )
 
-const frameWidth = 32
-const frameHeight = 8
-const frameArea = frameWidth * frameHeight
+// The size of a stock awtrix matrix, used when a device or file doesn't say otherwise
+const DefaultFrameWidth = 32
+const DefaultFrameHeight = 8
 
-type Frame [frameArea][3]uint8
+// Frame is a row-major grid of RGB pixels. Frames are not modified once they have been built.
+type Frame struct {
+	Width  int
+	Height int
+	Pixels [][3]uint8
+}
+
+func NewFrame(width int, height int) *Frame {
+	return &Frame{
+		Width:  width,
+		Height: height,
+		Pixels: make([][3]uint8, width*height),
+	}
+}
+
+func (f *Frame) Equal(other *Frame) bool {
+	return f.Width == other.Width && f.Height == other.Height && slices.Equal(f.Pixels, other.Pixels)
+}
+
+// Resize scales the frame to width x height. Each output pixel averages the source pixels it covers,
+// which is nearest neighbour when scaling up.
+func (f *Frame) Resize(width int, height int) *Frame {
+	if f.Width == width && f.Height == height {
+		return f
+	}
+
+	resized := NewFrame(width, height)
+
+	for y := 0; y < height; y++ {
+		y0 := y * f.Height / height
+		y1 := max((y+1)*f.Height/height, y0+1)
+
+		for x := 0; x < width; x++ {
+			x0 := x * f.Width / width
+			x1 := max((x+1)*f.Width/width, x0+1)
+
+			var sum [3]int
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pixel := f.Pixels[sy*f.Width+sx]
+					sum[0] += int(pixel[0])
+					sum[1] += int(pixel[1])
+					sum[2] += int(pixel[2])
+				}
+			}
+
+			count := (y1 - y0) * (x1 - x0)
+			resized.Pixels[y*width+x] = [3]uint8{uint8(sum[0] / count), uint8(sum[1] / count), uint8(sum[2] / count)}
+		}
+	}
+
+	return resized
+}
 
 func (f *Frame) SendFrame(url string) error {
-	colorVal := make([]string, frameArea)
+	colorVal := make([]string, len(f.Pixels))
 
-	for i, pixel := range f {
+	for i, pixel := range f.Pixels {
 		colorVal[i] = fmt.Sprint((uint32(pixel[0]) << 16) | (uint32(pixel[1]) << 8) | (uint32(pixel[2]) << 0))
 	}
 
-	jsonVal := fmt.Sprintf("{\"stack\":false,\"draw\":[{\"db\":[0,0,32,8,[%s]]}]}", strings.Join(colorVal, ","))
+	jsonVal := fmt.Sprintf("{\"stack\":false,\"draw\":[{\"db\":[0,0,%d,%d,[%s]]}]}", f.Width, f.Height, strings.Join(colorVal, ","))
 
-	_, err := http.Post(url, "application/json", strings.NewReader(jsonVal))
+	resp, err := Client.Post(url, "application/json", strings.NewReader(jsonVal))
 	if err != nil {
 		return err
 	}
 
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
 	return nil
 }
 
+// ReceiveFrame reads a screen into f, which must already have the screen's size.
 func (f *Frame) ReceiveFrame(url string) error {
-	resp, err := http.Get(url)
+	resp, err := Client.Get(url)
 	if err != nil {
 		return err
 	}
 
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
 
-	for index, v := range strings.Split(strings.Trim(string(body), "[]"), ",") {
+	values := strings.Split(strings.Trim(string(body), "[]"), ",")
+	if len(values) != len(f.Pixels) {
+		return fmt.Errorf("expected a %dx%d screen of %d pixels, got %d pixels", f.Width, f.Height, len(f.Pixels), len(values))
+	}
+
+	for index, v := range values {
 		num, err := strconv.ParseUint(v, 10, 24)
 		if err != nil {
 			return err
 		}
 
-		f[index] = [3]uint8{uint8((num & 0xFF0000) >> 16), uint8((num & 0x00FF00) >> 8), uint8((num & 0x0000FF) >> 0)}
+		f.Pixels[index] = [3]uint8{uint8((num & 0xFF0000) >> 16), uint8((num & 0x00FF00) >> 8), uint8((num & 0x0000FF) >> 0)}
 	}
 
 	return nil
 }
 
+// ReadScreen reads the screen of the clock at host, undoing mapping so it's the right way up.
+func ReadScreen(host string, device DeviceInfo, mapping PixelMapping) (*Frame, error) {
+	frame := NewFrame(device.Width, device.Height)
+
+	err := frame.ReceiveFrame(host + "/api/screen")
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.Invert(frame)
+}
+
 func (f *Frame) View() string {
 	var s strings.Builder
 
-	for i := 0; i < frameHeight; i++ {
-		for j := 0; j < frameWidth; j++ {
+	for i := 0; i < f.Height; i++ {
+		for j := 0; j < f.Width; j++ {
+			pixel := f.Pixels[i*f.Width+j]
 			s.WriteString(termenv.
 				String("██").
-				Foreground(termenv.ColorProfile().FromColor(color.RGBA{f[i*frameWidth+j][0], f[i*frameWidth+j][1], f[i*frameWidth+j][2], 255})).
+				Foreground(termenv.ColorProfile().FromColor(color.RGBA{pixel[0], pixel[1], pixel[2], 255})).
 				String(),
 			)
 		}
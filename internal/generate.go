@@ -1,17 +1,27 @@
 package internal
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"image"
 	"image/draw"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path"
+	"strings"
+	"sync"
 
 	"golang.org/x/image/bmp"
 )
 
-func GeneratePixelStream(sourceFile FileLocation, frameRate uint8) (*PixelStream, error) {
+func GeneratePixelStream(sourceFile FileLocation, frameRate uint8, width int, height int) (*PixelStream, error) {
+	return generatePixelStream(context.Background(), sourceFile, frameRate, width, height, false)
+}
+
+// generatePixelStream is GeneratePixelStream, keeping ffmpeg's output off the terminal when quiet. Cancelling ctx stops ffmpeg.
+func generatePixelStream(ctx context.Context, sourceFile FileLocation, frameRate uint8, width int, height int, quiet bool) (*PixelStream, error) {
 	if sourceFile.System != OS_FS {
 		return nil, fmt.Errorf("GeneratePixelStream source file must be from the OS FS")
 	}
@@ -23,10 +33,15 @@ func GeneratePixelStream(sourceFile FileLocation, frameRate uint8) (*PixelStream
 
 	defer os.RemoveAll(dirPath)
 
-	cmd := exec.Command("ffmpeg", "-i", sourceFile.ToOSPath(), "-filter:v", fmt.Sprintf("fps=%d,scale=32:8", frameRate), "-c:a", "copy", path.Join(dirPath, "%d.bmp"))
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-i", sourceFile.ToOSPath(), "-filter:v", fmt.Sprintf("fps=%d,scale=%d:%d", frameRate, width, height), "-an", path.Join(dirPath, "%d.bmp"))
+	if !quiet {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
 	err = cmd.Run()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -41,6 +56,8 @@ func GeneratePixelStream(sourceFile FileLocation, frameRate uint8) (*PixelStream
 	pixelstream := &PixelStream{
 		Version:   pixelstreamFormatVersion,
 		FrameRate: frameRate,
+		Width:     width,
+		Height:    height,
 		Frames:    make([]Frame, frameCount),
 	}
 
@@ -61,14 +78,116 @@ func GeneratePixelStream(sourceFile FileLocation, frameRate uint8) (*PixelStream
 		rgba := image.NewRGBA(rect)
 		draw.Draw(rgba, rect, img, rect.Min, draw.Src)
 
-		var frame Frame
+		frame := NewFrame(width, height)
 
 		for j := 0; j < len(rgba.Pix); j += 4 {
-			frame[j/4] = [3]uint8{rgba.Pix[j], rgba.Pix[j+1], rgba.Pix[j+2]}
+			frame.Pixels[j/4] = [3]uint8{rgba.Pix[j], rgba.Pix[j+1], rgba.Pix[j+2]}
 		}
 
-		pixelstream.Frames[i] = frame
+		pixelstream.Frames[i] = *frame
 	}
 
 	return pixelstream, nil
 }
+
+// LoadOrGenerate loads fl if it's a .pxlstrm file or has been converted before. Anything else is converted
+// at width x height and saved next to the original, so it only has to be converted once.
+func LoadOrGenerate(fl FileLocation, width int, height int) (*PixelStream, error) {
+	return loadOrGenerate(fl, width, height, false)
+}
+
+// loadOrGenerate is LoadOrGenerate, keeping ffmpeg's output off the terminal when quiet.
+func loadOrGenerate(fl FileLocation, width int, height int, quiet bool) (*PixelStream, error) {
+	if strings.HasSuffix(fl.Path, pixelstreamFileExt) {
+		return LoadFile(fl)
+	}
+
+	converted := FileLocation{
+		System: fl.System,
+		Path:   fl.Path + pixelstreamFileExt,
+	}
+
+	if _, err := fs.Stat(converted.System, converted.Path); !errors.Is(err, fs.ErrNotExist) {
+		return LoadFile(converted)
+	}
+
+	return ConvertFile(context.Background(), fl, width, height, quiet)
+}
+
+type conversion struct {
+	done        chan struct{}
+	pixelstream *PixelStream
+	err         error
+}
+
+var (
+	conversions      = make(map[string]*conversion)
+	conversionsMutex sync.Mutex
+)
+
+// ConvertFile converts a video at width x height and saves it next to the original as a .pxlstrm. If the video
+// is already being converted, such as in the background for a playlist, it waits for that conversion instead.
+func ConvertFile(ctx context.Context, fl FileLocation, width int, height int, quiet bool) (*PixelStream, error) {
+	converted := FileLocation{
+		System: fl.System,
+		Path:   fl.Path + pixelstreamFileExt,
+	}
+
+	conversionsMutex.Lock()
+	c, converting := conversions[converted.Path]
+	if !converting {
+		c = &conversion{done: make(chan struct{})}
+		conversions[converted.Path] = c
+	}
+	conversionsMutex.Unlock()
+
+	if converting {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		// A conversion that was cancelled, such as when a playlist was closed, is started over for whoever still wants it
+		if errors.Is(c.err, context.Canceled) && ctx.Err() == nil {
+			return ConvertFile(ctx, fl, width, height, quiet)
+		}
+
+		return c.pixelstream, c.err
+	}
+
+	defer func() {
+		conversionsMutex.Lock()
+		delete(conversions, converted.Path)
+		conversionsMutex.Unlock()
+		close(c.done)
+	}()
+
+	c.pixelstream, c.err = generatePixelStream(ctx, fl, DefaultFrameRate, width, height, quiet)
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	// Written under another name and moved into place, so the .pxlstrm never exists half written
+	partial := FileLocation{
+		System: converted.System,
+		Path:   converted.Path + ".part",
+	}
+
+	c.err = c.pixelstream.SaveFile(partial)
+	if c.err == nil {
+		c.err = os.Rename(partial.ToOSPath(), converted.ToOSPath())
+	}
+
+	return c.pixelstream, c.err
+}
+
+// IsConverted reports whether fl can be played without converting it first.
+func IsConverted(fl FileLocation) bool {
+	if strings.HasSuffix(fl.Path, pixelstreamFileExt) {
+		return true
+	}
+
+	_, err := fs.Stat(fl.System, fl.Path+pixelstreamFileExt)
+	return err == nil
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newTestClock starts a simulated clock and points Host at it for the duration of the test.
+func newTestClock(t *testing.T) *Simulator {
+	t.Helper()
+
+	return newTestClockWithSize(t, DefaultFrameWidth, DefaultFrameHeight)
+}
+
+// newTestClockWithSize is newTestClock for a clock with a width x height matrix.
+func newTestClockWithSize(t *testing.T, width int, height int) *Simulator {
+	t.Helper()
+
+	simulator := NewSimulatorWithSize(width, height)
+	server := httptest.NewServer(simulator)
+
+	previousHost := Host
+	previousDevice := Device
+	Host = server.URL
+
+	t.Cleanup(func() {
+		server.Close()
+		Host = previousHost
+		Device = previousDevice
+		savedDeviceState = nil
+	})
+
+	return simulator
+}
+
+// newTestStream returns a stream whose frames each have a different first pixel, so frames can be told apart.
+func newTestStream(frameCount int, frameRate uint8) *PixelStream {
+	ps := &PixelStream{
+		Version:   pixelstreamFormatVersion,
+		FrameRate: frameRate,
+		Width:     DefaultFrameWidth,
+		Height:    DefaultFrameHeight,
+		Frames:    make([]Frame, frameCount),
+	}
+
+	for i := range ps.Frames {
+		ps.Frames[i] = *NewFrame(ps.Width, ps.Height)
+		ps.Frames[i].Pixels[0] = [3]uint8{uint8(i), 0, 255}
+	}
+
+	return ps
+}
+
+// saveTestStream writes ps to a temporary .pxlstrm file and returns its location.
+func saveTestStream(t *testing.T, ps *PixelStream) FileLocation {
+	t.Helper()
+
+	path, err := filepath.Abs(filepath.Join(t.TempDir(), "test"+pixelstreamFileExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fl := FromOSPath(path)
+	err = ps.SaveFile(fl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fl
+}
+
+var cmdSliceType = reflect.TypeOf([]tea.Cmd(nil))
+
+// runCmd runs cmd and returns the messages it produces, expanding batches and sequences.
+func runCmd(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+
+	msg := cmd()
+	if msg == nil {
+		return nil
+	}
+
+	// tea.Sequence produces an unexported []tea.Cmd type, so it is matched by its underlying type
+	value := reflect.ValueOf(msg)
+	if value.Kind() == reflect.Slice && value.Type().ConvertibleTo(cmdSliceType) {
+		var msgs []tea.Msg
+		for _, c := range value.Convert(cmdSliceType).Interface().([]tea.Cmd) {
+			msgs = append(msgs, runCmd(c)...)
+		}
+		return msgs
+	}
+
+	return []tea.Msg{msg}
+}
+
+// update feeds every message to the model in order and returns the resulting model and commands.
+func update(model tea.Model, msgs ...tea.Msg) (tea.Model, []tea.Cmd) {
+	var cmds []tea.Cmd
+	for _, msg := range msgs {
+		var cmd tea.Cmd
+		model, cmd = model.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	return model, cmds
+}
+
+func keyPress(key string) tea.KeyMsg {
+	switch key {
+	case "left":
+		return tea.KeyMsg{Type: tea.KeyLeft}
+	case "right":
+		return tea.KeyMsg{Type: tea.KeyRight}
+	case "up":
+		return tea.KeyMsg{Type: tea.KeyUp}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case " ":
+		return tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}
+	}
+
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+}
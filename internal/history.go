@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// HistoryFile is where the last position of each played file is kept. History is off when it's empty.
+var HistoryFile string
+
+const (
+	maxHistory = 50
+	// Positions this close to the start or end aren't worth resuming from
+	resumeMargin = time.Second * 10
+	// Only this much of the start and end of a file is hashed, so big videos don't have to be read in full
+	historyHashChunk = 64 * 1024
+)
+
+// HistoryEntry is the position a file was last left at. The hash tells a file apart from another one later saved to the same path.
+type HistoryEntry struct {
+	Path     string        `json:"path"`
+	Hash     string        `json:"hash"`
+	Position time.Duration `json:"position"`
+	Duration time.Duration `json:"duration"`
+	PlayedAt time.Time     `json:"playedAt"`
+}
+
+// The history is read and written whole, so saves from the UI and from quitting don't overwrite each other
+var historyMutex sync.Mutex
+
+// LoadHistory returns the files played most recently first.
+func LoadHistory() ([]HistoryEntry, error) {
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+
+	return loadHistory()
+}
+
+func loadHistory() ([]HistoryEntry, error) {
+	if HistoryFile == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(HistoryFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []HistoryEntry
+	err = json.Unmarshal(data, &entries)
+	return entries, err
+}
+
+// SavePosition records where fl was left, moving it to the top of the history. Only files on disk are kept.
+func SavePosition(fl FileLocation, position time.Duration, duration time.Duration) error {
+	if HistoryFile == "" || fl.System != OS_FS {
+		return nil
+	}
+
+	hash, err := hashFile(fl.ToOSPath())
+	if err != nil {
+		return err
+	}
+
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+
+	// A history that can't be read is started over rather than getting in the way of playing
+	entries, _ := loadHistory()
+
+	updated := []HistoryEntry{{
+		Path:     fl.ToOSPath(),
+		Hash:     hash,
+		Position: position,
+		Duration: duration,
+		PlayedAt: time.Now(),
+	}}
+	for _, entry := range entries {
+		if entry.Path != fl.ToOSPath() && len(updated) < maxHistory {
+			updated = append(updated, entry)
+		}
+	}
+
+	data, err := json.MarshalIndent(updated, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(HistoryFile), 0755)
+	if err != nil {
+		return err
+	}
+
+	part := HistoryFile + ".part"
+	err = os.WriteFile(part, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(part, HistoryFile)
+}
+
+// ResumePosition returns where fl was left last time, if it's the same file and it wasn't left near the start or end.
+func ResumePosition(fl FileLocation) (time.Duration, bool) {
+	if HistoryFile == "" || fl.System != OS_FS {
+		return 0, false
+	}
+
+	entries, err := LoadHistory()
+	if err != nil {
+		return 0, false
+	}
+
+	for _, entry := range entries {
+		if entry.Path != fl.ToOSPath() {
+			continue
+		}
+
+		if entry.Position < resumeMargin || entry.Position > entry.Duration-resumeMargin {
+			return 0, false
+		}
+
+		hash, err := hashFile(entry.Path)
+		if err != nil || hash != entry.Hash {
+			return 0, false
+		}
+
+		return entry.Position, true
+	}
+
+	return 0, false
+}
+
+// hashFile hashes the size of the file along with its first and last chunks.
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	binary.Write(h, binary.LittleEndian, info.Size())
+
+	_, err = io.Copy(h, io.LimitReader(file, historyHashChunk))
+	if err != nil {
+		return "", err
+	}
+
+	if info.Size() > historyHashChunk*2 {
+		_, err = io.Copy(h, io.NewSectionReader(file, info.Size()-historyHashChunk, historyHashChunk))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newTestHistory points the history at a file in a temporary directory for the length of the test.
+func newTestHistory(t *testing.T) {
+	t.Helper()
+
+	previous := HistoryFile
+	HistoryFile = filepath.Join(t.TempDir(), "pixelstream", "history.json")
+	t.Cleanup(func() { HistoryFile = previous })
+}
+
+func TestHistory(t *testing.T) {
+	newTestHistory(t)
+
+	first := saveTestStream(t, newTestStream(16, 16))
+	second := saveTestStream(t, newTestStream(32, 16))
+
+	if _, ok := ResumePosition(first); ok {
+		t.Error("expected nothing to resume before anything is played")
+	}
+
+	for _, save := range []struct {
+		fl       FileLocation
+		position time.Duration
+	}{
+		{first, time.Second * 20},
+		{second, time.Second * 30},
+		{first, time.Minute},
+	} {
+		err := SavePosition(save.fl, save.position, time.Minute*10)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := LoadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Path != first.ToOSPath() || entries[1].Path != second.ToOSPath() {
+		t.Fatalf("expected each file once, most recent first, got %+v", entries)
+	}
+
+	if position, ok := ResumePosition(first); !ok || position != time.Minute {
+		t.Errorf("expected to resume from 1m, got %s", position)
+	}
+
+	// Near the start or end there's nothing to resume
+	SavePosition(second, time.Minute*10-time.Second, time.Minute*10)
+	if _, ok := ResumePosition(second); ok {
+		t.Error("expected a file left at the end not to be resumed")
+	}
+
+	// Another file saved over the first isn't resumed
+	err = newTestStream(24, 16).SaveFile(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ResumePosition(first); ok {
+		t.Error("expected a changed file not to be resumed")
+	}
+
+	for i := 0; i < maxHistory+5; i++ {
+		fl := FromOSPath(filepath.Join(t.TempDir(), "file.pxlstrm"))
+		os.WriteFile(fl.ToOSPath(), []byte{byte(i)}, 0644)
+		SavePosition(fl, time.Second*20, time.Minute)
+	}
+	if entries, _ := LoadHistory(); len(entries) != maxHistory {
+		t.Errorf("expected the history to be cut down to %d files, got %d", maxHistory, len(entries))
+	}
+}
+
+func TestPlayModeResumes(t *testing.T) {
+	newTestClock(t)
+	newTestHistory(t)
+
+	fl := saveTestStream(t, newTestStream(16*60, 16))
+	SavePosition(fl, time.Second*30, time.Minute)
+
+	m, msgs := loadPlayMode(t, fl)
+	t.Cleanup(m.player.Close)
+	model, _ := update(m, msgs...)
+	m = model.(PlayMode)
+
+	if m.player.Playing() || !strings.Contains(m.View(), "Resume from 00:00:30? [y/n]") {
+		t.Fatalf("expected playback to wait on the offer to resume, got:\n%s", m.View())
+	}
+
+	model, cmd := m.Update(keyPress("y"))
+	model, _ = update(model, runCmd(cmd)...)
+	m = model.(PlayMode)
+	if !m.player.Playing() || m.player.Position() < time.Second*30 || m.player.Position() > time.Second*31 {
+		t.Errorf("expected playback to resume from 30s, got %s", m.player.Position())
+	}
+
+	m.player.Seek(time.Second * 40)
+	m.closePlayer()
+
+	recent, _ := update(NewRecentMode(), runCmd(NewRecentMode().Init())...)
+	if view := recent.View(); !strings.Contains(view, "test.pxlstrm  00:00:40 / 00:01:00") {
+		t.Errorf("expected Recent to list the file where it was left, got:\n%s", view)
+	}
+
+	model, cmd = recent.Update(keyPress("enter"))
+	if model.(PlayMode).file.Path != fl.Path || cmd == nil {
+		t.Error("expected enter to play the file")
+	}
+}
+
+func TestPlayModeStartsOver(t *testing.T) {
+	newTestClock(t)
+	newTestHistory(t)
+
+	fl := saveTestStream(t, newTestStream(16*60, 16))
+	SavePosition(fl, time.Second*30, time.Minute)
+
+	m, msgs := loadPlayMode(t, fl)
+	t.Cleanup(m.player.Close)
+	model, _ := update(m, msgs...)
+
+	// Keys other than the answer are left alone while it's offered
+	model, _ = update(model, keyPress("l"), tea.MouseMsg{Button: tea.MouseButtonLeft, Action: tea.MouseActionPress})
+	model, cmd := model.Update(keyPress("n"))
+	model, _ = update(model, runCmd(cmd)...)
+	m = model.(PlayMode)
+
+	if !m.player.Playing() || m.player.Position() > time.Second {
+		t.Errorf("expected playback to start over, got %s", m.player.Position())
+	}
+}
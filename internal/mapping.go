@@ -0,0 +1,213 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type Serpentine int
+
+const (
+	SerpentineNone Serpentine = iota
+	// Every other row runs right to left, as is common for matrices wired as a single strip
+	SerpentineRows
+	// LEDs run down the columns, and every other column runs bottom to top
+	SerpentineColumns
+)
+
+// PixelMapping describes how a matrix is mounted and wired, so frames can be reordered into the order its LEDs expect.
+// The frame is rotated and flipped first, then laid out by the wiring: serpentine rows or columns, or a lookup table.
+type PixelMapping struct {
+	Rotate180  bool
+	FlipH      bool
+	FlipV      bool
+	Serpentine Serpentine
+	// Lookup[n] is the LED that shows pixel n, counting pixels in reading order
+	Lookup []int
+}
+
+// Mapping is the pixel mapping of the clock at Host, applied to frames sent to it and inverted on frames read from it.
+var Mapping PixelMapping
+
+// ParsePixelMapping reads a mapping from query parameters: rotate=180, flip=h, flip=v, flip=hv, serpentine=rows,
+// serpentine=columns, and lut=path for a lookup table file. serpentine=true is the same as serpentine=rows.
+func ParsePixelMapping(query url.Values) (PixelMapping, error) {
+	var pm PixelMapping
+
+	switch query.Get("rotate") {
+	case "", "0":
+	case "180":
+		pm.Rotate180 = true
+	default:
+		return pm, fmt.Errorf("unsupported rotation %q, expected 0 or 180", query.Get("rotate"))
+	}
+
+	switch query.Get("flip") {
+	case "":
+	case "h":
+		pm.FlipH = true
+	case "v":
+		pm.FlipV = true
+	case "hv", "vh":
+		pm.FlipH = true
+		pm.FlipV = true
+	default:
+		return pm, fmt.Errorf("unsupported flip %q, expected h, v or hv", query.Get("flip"))
+	}
+
+	switch serpentine := query.Get("serpentine"); serpentine {
+	case "rows":
+		pm.Serpentine = SerpentineRows
+	case "columns":
+		pm.Serpentine = SerpentineColumns
+	case "":
+	default:
+		rows, err := strconv.ParseBool(serpentine)
+		if err != nil {
+			return pm, fmt.Errorf("invalid serpentine value %q, expected rows or columns", serpentine)
+		}
+		if rows {
+			pm.Serpentine = SerpentineRows
+		}
+	}
+
+	if path := query.Get("lut"); path != "" {
+		if pm.Serpentine != SerpentineNone {
+			return pm, errors.New("serpentine and lut can't be used together")
+		}
+
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return pm, err
+		}
+
+		pm.Lookup, err = LoadPixelLookup(FromOSPath(path))
+		if err != nil {
+			return pm, err
+		}
+	}
+
+	return pm, nil
+}
+
+// LoadPixelLookup reads a lookup table of LED numbers separated by whitespace or commas. Lines starting with # are ignored.
+// The table has to number every LED exactly once.
+func LoadPixelLookup(fl FileLocation) ([]int, error) {
+	file, err := fs.ReadFile(fl.System, fl.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lookup []int
+	for _, line := range strings.Split(string(file), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		for _, field := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\r' }) {
+			led, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid LED number %q in lookup table", field)
+			}
+			lookup = append(lookup, led)
+		}
+	}
+
+	seen := make([]bool, len(lookup))
+	for _, led := range lookup {
+		if led < 0 || led >= len(lookup) || seen[led] {
+			return nil, fmt.Errorf("lookup table has to number each of its %d LEDs once, found %d", len(lookup), led)
+		}
+		seen[led] = true
+	}
+
+	return lookup, nil
+}
+
+func (pm PixelMapping) isIdentity() bool {
+	return !pm.Rotate180 && !pm.FlipH && !pm.FlipV && pm.Serpentine == SerpentineNone && pm.Lookup == nil
+}
+
+// sources returns, for each LED of a width x height matrix, the index of the frame pixel it shows.
+func (pm PixelMapping) sources(width int, height int) ([]int, error) {
+	if pm.Lookup != nil && len(pm.Lookup) != width*height {
+		return nil, fmt.Errorf("lookup table has %d LEDs but the frame is %dx%d", len(pm.Lookup), width, height)
+	}
+
+	sources := make([]int, width*height)
+
+	// i counts LEDs, or pixels when there's a lookup table since it goes from pixels to LEDs
+	for i := range sources {
+		x, y := i%width, i/width
+
+		switch pm.Serpentine {
+		case SerpentineRows:
+			if y%2 == 1 {
+				x = width - 1 - x
+			}
+		case SerpentineColumns:
+			x, y = i/height, i%height
+			if x%2 == 1 {
+				y = height - 1 - y
+			}
+		}
+
+		if pm.FlipH != pm.Rotate180 {
+			x = width - 1 - x
+		}
+		if pm.FlipV != pm.Rotate180 {
+			y = height - 1 - y
+		}
+
+		if pm.Lookup != nil {
+			sources[pm.Lookup[i]] = y*width + x
+		} else {
+			sources[i] = y*width + x
+		}
+	}
+
+	return sources, nil
+}
+
+// Apply returns a copy of frame with its pixels in LED order.
+func (pm PixelMapping) Apply(frame *Frame) (*Frame, error) {
+	if pm.isIdentity() {
+		return frame, nil
+	}
+
+	sources, err := pm.sources(frame.Width, frame.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	mapped := NewFrame(frame.Width, frame.Height)
+	for led, source := range sources {
+		mapped.Pixels[led] = frame.Pixels[source]
+	}
+
+	return mapped, nil
+}
+
+// Invert undoes Apply, turning pixels in LED order, like a screen read back from a clock, into a frame.
+func (pm PixelMapping) Invert(frame *Frame) (*Frame, error) {
+	if pm.isIdentity() {
+		return frame, nil
+	}
+
+	sources, err := pm.sources(frame.Width, frame.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	unmapped := NewFrame(frame.Width, frame.Height)
+	for led, source := range sources {
+		unmapped.Pixels[source] = frame.Pixels[led]
+	}
+
+	return unmapped, nil
+}
@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPixelMapping(t *testing.T) {
+	lut := filepath.Join(t.TempDir(), "leds.txt")
+	err := os.WriteFile(lut, []byte("# wired right to left\n2, 1, 0\n5, 4, 3\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A 3x2 frame, numbered in reading order:
+	// 0 1 2
+	// 3 4 5
+	frame := newGradientFrame(3, 2)
+
+	for query, expected := range map[string][]uint8{
+		"":                           {0, 1, 2, 3, 4, 5},
+		"serpentine=true":            {0, 1, 2, 5, 4, 3},
+		"serpentine=columns":         {0, 3, 4, 1, 2, 5},
+		"rotate=180":                 {5, 4, 3, 2, 1, 0},
+		"flip=h":                     {2, 1, 0, 5, 4, 3},
+		"flip=v":                     {3, 4, 5, 0, 1, 2},
+		"flip=hv":                    {5, 4, 3, 2, 1, 0},
+		"flip=v&serpentine=rows":     {3, 4, 5, 2, 1, 0},
+		"rotate=180&flip=h":          {3, 4, 5, 0, 1, 2},
+		"lut=" + lut:                 {2, 1, 0, 5, 4, 3},
+		"lut=" + lut + "&flip=v":     {5, 4, 3, 2, 1, 0},
+		"rotate=180&serpentine=on":   nil,
+		"serpentine=rows&lut=" + lut: nil,
+		"lut=" + filepath.Join(t.TempDir(), "missing.txt"): nil,
+	} {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mapping, err := ParsePixelMapping(values)
+		if expected == nil {
+			if err == nil {
+				t.Errorf("%q: expected an error", query)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", query, err)
+			continue
+		}
+
+		mapped, err := mapping.Apply(frame)
+		if err != nil {
+			t.Errorf("%q: %s", query, err)
+			continue
+		}
+
+		for i, pixel := range mapped.Pixels {
+			if pixel[0] != expected[i] {
+				t.Errorf("%q: expected LED order %v, got pixel %d at %d", query, expected, pixel[0], i)
+				break
+			}
+		}
+
+		unmapped, err := mapping.Invert(mapped)
+		if err != nil || !unmapped.Equal(frame) {
+			t.Errorf("%q: expected Invert to undo Apply", query)
+		}
+	}
+}
+
+func TestPixelLookupValidation(t *testing.T) {
+	for name, contents := range map[string]string{
+		"duplicate":    "0 1 1",
+		"out of range": "0 1 3",
+		"not a number": "0 one 2",
+	} {
+		path := filepath.Join(t.TempDir(), "leds.txt")
+		err := os.WriteFile(path, []byte(contents), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = LoadPixelLookup(FromOSPath(abs))
+		if err == nil {
+			t.Errorf("%s: expected the lookup table to be rejected", name)
+		}
+	}
+
+	mapping := PixelMapping{Lookup: []int{0, 1, 2}}
+	_, err := mapping.Apply(newGradientFrame(3, 2))
+	if err == nil {
+		t.Error("expected a lookup table of the wrong size to be rejected")
+	}
+}
+
+func TestViewModeInvertsMapping(t *testing.T) {
+	clock := newTestClock(t)
+
+	previousMapping := Mapping
+	t.Cleanup(func() { Mapping = previousMapping })
+	Mapping = PixelMapping{Rotate180: true, Serpentine: SerpentineRows}
+
+	frame := newGradientFrame(DefaultFrameWidth, DefaultFrameHeight)
+	err := output().Send(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	screen := clock.Screen()
+	if screen.Equal(frame) {
+		t.Fatal("expected the clock to receive the frame in LED order")
+	}
+
+	m := NewViewMode(DefaultViewPollInterval)
+	model, _ := m.Update(m.fetchFrame())
+	m = model.(ViewMode)
+
+	if !m.currentFrame.Equal(frame) {
+		t.Error("expected ViewMode to show the frame the right way up")
+	}
+}
@@ -58,7 +58,8 @@ func NewMenuMode() MenuMode {
 		listItems[i] = v
 	}
 
-	l := list.New(listItems, itemDelegate{}, 80, 7)
+	// Tall enough for every item, with room for the list's help line underneath
+	l := list.New(listItems, itemDelegate{}, 80, len(MenuItems)+4)
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(false)
 	l.SetShowTitle(false)
@@ -106,8 +107,13 @@ func (m MenuMode) View() string {
 	var s strings.Builder
 
 	s.WriteString("pixelstream - Stream videos to your awtrix clock with ease.\n")
-	s.WriteString("Host: ")
-	s.WriteString(Host)
+	if Host == "" && Output != nil {
+		s.WriteString("Output: ")
+		s.WriteString(Output.String())
+	} else {
+		s.WriteString("Host: ")
+		s.WriteString(RedactHost(Host))
+	}
 	s.WriteString("\n\n")
 
 	s.WriteString(m.list.View())
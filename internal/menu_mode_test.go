@@ -0,0 +1,24 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMenuModeShowsEveryItem(t *testing.T) {
+	previous := MenuItems
+	t.Cleanup(func() { MenuItems = previous })
+
+	MenuItems = nil
+	for i := 0; i < 8; i++ {
+		MenuItems = append(MenuItems, MenuItem{Label: fmt.Sprintf("Item %d", i), Mode: NewMenuMode()})
+	}
+
+	view := NewMenuMode().View()
+	for _, item := range MenuItems {
+		if !strings.Contains(view, item.Label) {
+			t.Errorf("expected %s to be shown, got:\n%s", item.Label, view)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMPVSocket is where mpv listens when started with --input-ipc-server=/tmp/mpvsocket
+const DefaultMPVSocket = "/tmp/mpvsocket"
+
+// MPVState is what a followed mpv player is doing.
+type MPVState struct {
+	// The loaded file, absolute when it's on disk. Empty when nothing is loaded.
+	Path     string
+	Position time.Duration
+	Paused   bool
+}
+
+// The properties observed, numbered by their index + 1. working-directory comes first, so relative paths can be resolved.
+var mpvProperties = []string{"working-directory", "path", "time-pos", "pause"}
+
+type mpvEvent struct {
+	Event string          `json:"event"`
+	Name  string          `json:"name"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// MPVClient follows an mpv player through its JSON IPC socket.
+type MPVClient struct {
+	conn   net.Conn
+	states chan MPVState
+
+	mutex sync.Mutex
+	err   error
+}
+
+// DialMPV connects to the IPC socket of a running mpv and starts observing it.
+func DialMPV(socket string) (*MPVClient, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, name := range mpvProperties {
+		command, _ := json.Marshal(map[string][]any{"command": {"observe_property", i + 1, name}})
+		_, err = conn.Write(append(command, '\n'))
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	c := &MPVClient{
+		conn:   conn,
+		states: make(chan MPVState, 1),
+	}
+	go c.read()
+
+	return c, nil
+}
+
+// States receives mpv's state whenever it changes. States that weren't received in time are replaced by newer ones.
+// It's closed when mpv goes away, after which Err says why.
+func (c *MPVClient) States() <-chan MPVState {
+	return c.states
+}
+
+func (c *MPVClient) Err() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.err
+}
+
+func (c *MPVClient) Close() error {
+	return c.conn.Close()
+}
+
+func (c *MPVClient) read() {
+	defer close(c.states)
+
+	var state MPVState
+	var workingDirectory, path string
+
+	scanner := bufio.NewScanner(c.conn)
+	for scanner.Scan() {
+		var event mpvEvent
+		if json.Unmarshal(scanner.Bytes(), &event) != nil || event.Event != "property-change" {
+			continue
+		}
+
+		// Data is missing or null while a property is unavailable, such as time-pos with nothing loaded
+		switch event.Name {
+		case "working-directory":
+			workingDirectory = ""
+			json.Unmarshal(event.Data, &workingDirectory)
+		case "path":
+			path = ""
+			json.Unmarshal(event.Data, &path)
+		case "time-pos":
+			var seconds float64
+			json.Unmarshal(event.Data, &seconds)
+			state.Position = time.Duration(seconds * float64(time.Second))
+		case "pause":
+			json.Unmarshal(event.Data, &state.Paused)
+		default:
+			continue
+		}
+
+		// Streams like https:// URLs are passed on as they are
+		state.Path = path
+		if path != "" && !filepath.IsAbs(path) && !strings.Contains(path, "://") && workingDirectory != "" {
+			state.Path = filepath.Join(workingDirectory, path)
+		}
+
+		// Only this goroutine sends, so there's room once the stale state is taken out
+		select {
+		case <-c.states:
+		default:
+		}
+		c.states <- state
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.err = scanner.Err()
+	if c.err == nil {
+		c.err = errors.New("mpv closed the connection")
+	}
+}
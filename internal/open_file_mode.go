@@ -1,11 +1,15 @@
 package internal
 
 import (
+	"fmt"
 	"io/fs"
+	"path/filepath"
 	"pixelstream/charmbracelet/bubbles/filepicker"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -13,17 +17,41 @@ func NewOpenFileMode(fs fs.FS, initDir string) OpenFileMode {
 	fp := filepicker.New(fs)
 	fp.CurrentDirectory = initDir
 	fp.ShowPermissions = false
-	fp.AllowedTypes = []string{".pxlstrm", ".mp4", ".mkv", ".webm"}
+	fp.AllowedTypes = append([]string{".pxlstrm", ".mp4", ".mkv", ".webm"}, playlistFileExts...)
 
 	return OpenFileMode{
 		filepicker: fp,
+		keymap: OpenFileModeKeymap{
+			add: key.NewBinding(
+				key.WithKeys("a"),
+				key.WithHelp("a", "add to queue"),
+			),
+			playQueue: key.NewBinding(
+				key.WithKeys("p"),
+				key.WithHelp("p", "play queue"),
+			),
+			clearQueue: key.NewBinding(
+				key.WithKeys("c"),
+				key.WithHelp("c", "clear queue"),
+			),
+		},
+		help: help.New(),
 	}
 }
 
 type OpenFileMode struct {
 	filepicker   filepicker.Model
 	selectedFile string
+	message      string
 	err          error
+	keymap       OpenFileModeKeymap
+	help         help.Model
+}
+
+type OpenFileModeKeymap struct {
+	add        key.Binding
+	playQueue  key.Binding
+	clearQueue key.Binding
 }
 
 type clearErrorMsg struct{}
@@ -49,6 +77,18 @@ func (m OpenFileMode) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return NewMenuMode(), nil
 		}
 
+		switch {
+		case key.Matches(msg, m.keymap.add):
+			return m.addToQueue()
+		case key.Matches(msg, m.keymap.playQueue) && Queue.Len() > 0:
+			Queue.Jump(0)
+			return SwitchMode(NewPlaylistMode(Queue))
+		case key.Matches(msg, m.keymap.clearQueue):
+			Queue.Clear()
+			m.message = "Cleared the queue"
+			return m, nil
+		}
+
 	case clearErrorMsg:
 		m.err = nil
 	}
@@ -57,20 +97,75 @@ func (m OpenFileMode) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	m.filepicker, cmd = m.filepicker.Update(msg)
 
 	if didSelect, path := m.filepicker.DidSelectFile(msg); didSelect {
-		println(path)
-		return SwitchMode(NewPlayMode(FileLocation{
+		fl := FileLocation{
 			System: m.filepicker.FS,
 			Path:   path,
-		}))
+		}
+
+		if !IsPlaylistFile(fl) {
+			return SwitchMode(NewPlayMode(fl))
+		}
+
+		playlist, err := LoadPlaylist(fl)
+		if err != nil {
+			m.err = err
+			return m, clearErrorAfter(time.Second * 5)
+		}
+
+		return SwitchMode(NewPlaylistMode(playlist))
 	}
 
 	return m, cmd
 }
 
+// addToQueue adds the highlighted file to the queue, or everything in it if it's a playlist.
+func (m OpenFileMode) addToQueue() (tea.Model, tea.Cmd) {
+	didSelect, path := m.filepicker.HighlightedFile()
+	if !didSelect {
+		return m, nil
+	}
+
+	fl := FileLocation{
+		System: m.filepicker.FS,
+		Path:   path,
+	}
+
+	if !IsPlaylistFile(fl) {
+		Queue.Add(fl)
+		m.message = fmt.Sprintf("Added %s to the queue", filepath.Base(path))
+		return m, nil
+	}
+
+	playlist, err := LoadPlaylist(fl)
+	if err != nil {
+		m.err = err
+		return m, clearErrorAfter(time.Second * 5)
+	}
+
+	for _, item := range playlist.Items {
+		Queue.Add(item)
+	}
+	m.message = fmt.Sprintf("Added %d files from %s to the queue", playlist.Len(), filepath.Base(path))
+	return m, nil
+}
+
 func (m OpenFileMode) View() string {
 	var s strings.Builder
 	s.WriteString("\n  ")
 	s.WriteString(m.filepicker.Styles.Directory.Render(m.filepicker.CurrentDirectory))
 	s.WriteString("\n\n" + m.filepicker.View() + "\n")
+
+	if m.err != nil {
+		s.WriteString("  " + m.err.Error() + "\n")
+	} else if m.message != "" {
+		s.WriteString("  " + m.message + "\n")
+	}
+
+	s.WriteString(fmt.Sprintf("  %d in the queue\n\n  ", Queue.Len()))
+	s.WriteString(m.help.ShortHelpView([]key.Binding{
+		m.keymap.add,
+		m.keymap.playQueue,
+		m.keymap.clearQueue,
+	}))
 	return s.String()
 }
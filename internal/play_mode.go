@@ -1,9 +1,12 @@
 package internal
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io/fs"
-	"pixelstream/charmbracelet/bubbles/stopwatch"
+	"path"
+	"slices"
 	"strings"
 	"time"
 
@@ -11,6 +14,7 @@ import (
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -25,17 +29,37 @@ const (
 )
 
 type PlayMode struct {
-	state         playModeState
-	stateMessage  string
-	spinner       spinner.Model
-	file          FileLocation
-	pixelstream   *PixelStream
-	frame         *Frame
-	stopwatch     stopwatch.Model
-	keymap        PlayModeKeymap
-	help          help.Model
-	progress      progress.Model
-	sendFrameLock *CmdLock
+	state        playModeState
+	stateMessage string
+	spinner      spinner.Model
+	file         FileLocation
+	playlist     *Playlist
+	pixelstream  *PixelStream
+	player       *Player
+	audio        *ExternalAudio
+	audioErr     error
+	frame        *Frame
+	frameIndex   int
+	position     time.Duration
+	seekStep     time.Duration
+	// The go to timestamp prompt, which takes the keys while it's open
+	prompt    textinput.Model
+	prompting bool
+	stats     PlaybackStats
+	showStats bool
+	// Where the mouse is over the progress bar, and whether it's being dragged along it
+	hover    time.Duration
+	hovering bool
+	dragging bool
+	// Where the file was left last time, which playback waits on while it's offered
+	resume         time.Duration
+	offeringResume bool
+	// The A marker of an A-B repeat that's waiting for its B
+	markA    time.Duration
+	markingB bool
+	keymap   PlayModeKeymap
+	help     help.Model
+	progress progress.Model
 }
 
 type PlayModeKeymap struct {
@@ -45,20 +69,53 @@ type PlayModeKeymap struct {
 	quit          key.Binding
 	skipBackwards key.Binding
 	skipForwards  key.Binding
+	stats         key.Binding
+	adaptive      key.Binding
+	audioEarlier  key.Binding
+	audioLater    key.Binding
+	next          key.Binding
+	previous      key.Binding
+	shuffle       key.Binding
+	repeat        key.Binding
+	loop          key.Binding
+	abRepeat      key.Binding
+	slower        key.Binding
+	faster        key.Binding
+	reverse       key.Binding
+	frameBack     key.Binding
+	frameForward  key.Binding
+	cycleStep     key.Binding
+	jump          key.Binding
+	goTo          key.Binding
 }
 
-const defaultFrameRate = 16
+// The seek steps that t cycles through, starting from the second
+var playModeSeekSteps = []time.Duration{time.Second, time.Second * 5, time.Second * 10, time.Minute}
+
+// The speeds that - and + step through
+var playModeSpeeds = []float64{0.25, 0.5, 0.75, 1, 1.25, 1.5, 2, 3, 4}
+
+// How much [ and ] move the audio
+const audioOffsetStep = time.Millisecond * 25
+
+const DefaultFrameRate = 16
 
 func NewPlayMode(file FileLocation) PlayMode {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
 
+	prompt := textinput.New()
+	prompt.Prompt = "Go to: "
+	prompt.Placeholder = "h:mm:ss"
+
 	m := PlayMode{
-		state:   playModeLoading,
-		spinner: s,
-		file:    file,
-		frame:   &Frame{},
+		state:    playModeLoading,
+		spinner:  s,
+		file:     file,
+		frame:    NewFrame(Device.Width, Device.Height),
+		seekStep: playModeSeekSteps[1],
+		prompt:   prompt,
 		keymap: PlayModeKeymap{
 			start: key.NewBinding(
 				key.WithKeys(" ", "k"),
@@ -78,53 +135,233 @@ func NewPlayMode(file FileLocation) PlayMode {
 			),
 			skipBackwards: key.NewBinding(
 				key.WithKeys("left", "j"),
-				key.WithHelp("←/j", "backwards"),
 			),
 			skipForwards: key.NewBinding(
 				key.WithKeys("right", "l"),
-				key.WithHelp("→/l", "forwards"),
+			),
+			stats: key.NewBinding(
+				key.WithKeys("s"),
+				key.WithHelp("s", "stats"),
+			),
+			adaptive: key.NewBinding(
+				key.WithKeys("a"),
+				key.WithHelp("a", "adaptive rate"),
+			),
+			audioEarlier: key.NewBinding(
+				key.WithKeys("["),
+				key.WithHelp("[/]", "audio offset"),
+			),
+			audioLater: key.NewBinding(
+				key.WithKeys("]"),
+			),
+			next: key.NewBinding(
+				key.WithKeys("n"),
+				key.WithHelp("n/p", "next/previous"),
+			),
+			previous: key.NewBinding(
+				key.WithKeys("p"),
+			),
+			shuffle: key.NewBinding(
+				key.WithKeys("z"),
+				key.WithHelp("z", "shuffle"),
+			),
+			repeat: key.NewBinding(
+				key.WithKeys("x"),
+				key.WithHelp("x", "repeat"),
+			),
+			loop: key.NewBinding(
+				key.WithKeys("o"),
+				key.WithHelp("o", "loop"),
+			),
+			abRepeat: key.NewBinding(
+				key.WithKeys("b"),
+				key.WithHelp("b", "a-b repeat"),
+			),
+			slower: key.NewBinding(
+				key.WithKeys("-"),
+				key.WithHelp("-/+", "speed"),
+			),
+			faster: key.NewBinding(
+				key.WithKeys("+", "="),
+			),
+			reverse: key.NewBinding(
+				key.WithKeys("v"),
+				key.WithHelp("v", "reverse"),
+			),
+			frameBack: key.NewBinding(
+				key.WithKeys(","),
+				key.WithHelp(",/.", "frame"),
+			),
+			frameForward: key.NewBinding(
+				key.WithKeys("."),
+			),
+			cycleStep: key.NewBinding(
+				key.WithKeys("t"),
+				key.WithHelp("t", "seek step"),
+			),
+			jump: key.NewBinding(
+				key.WithKeys("0", "1", "2", "3", "4", "5", "6", "7", "8", "9"),
+				key.WithHelp("0-9", "jump to %"),
+			),
+			goTo: key.NewBinding(
+				key.WithKeys("g"),
+				key.WithHelp("g", "go to"),
 			),
 		},
-		help:          help.New(),
-		progress:      progress.New(progress.WithoutPercentage(), progress.WithWidth(46), progress.WithScaledGradient("#FF7CCB", "#FDFF8C")),
-		sendFrameLock: &CmdLock{},
+		help:     help.New(),
+		progress: progress.New(progress.WithoutPercentage(), progress.WithWidth(46), progress.WithScaledGradient("#FF7CCB", "#FDFF8C")),
 	}
 
 	m.keymap.start.SetEnabled(false)
+	m = m.setSeekStep(m.seekStep)
+	m.keymap.audioEarlier.SetEnabled(false)
+	m.keymap.audioLater.SetEnabled(false)
+	m.keymap.next.SetEnabled(false)
+	m.keymap.previous.SetEnabled(false)
+	m.keymap.shuffle.SetEnabled(false)
+	m.keymap.repeat.SetEnabled(false)
+
+	return m
+}
+
+// NewPlaylistMode plays the current file of playlist, and moves on through the playlist from there.
+func NewPlaylistMode(playlist *Playlist) PlayMode {
+	m := NewPlayMode(playlist.Current())
+	m.playlist = playlist
+
+	m.keymap.next.SetEnabled(true)
+	m.keymap.previous.SetEnabled(true)
+	m.keymap.shuffle.SetEnabled(true)
+	m.keymap.repeat.SetEnabled(true)
 
 	return m
 }
 
+// PlayMode takes the whole screen, so the mouse can be placed on the preview and progress bar.
 func (m PlayMode) Init() tea.Cmd {
-	return m.LoadFile()
+	return tea.Batch(m.LoadFile(), tea.EnterAltScreen, tea.EnableMouseAllMotion)
 }
 
 func (m PlayMode) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	var cmd tea.Cmd
-
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.prompting && msg.String() != "ctrl+c" {
+			return m.updatePrompt(msg)
+		}
+
 		switch msg.String() {
 		case "ctrl+c":
-			return m, tea.Quit
+			return m, tea.Sequence(m.closePlayer, tea.Quit)
 
 		case "q":
-			return NewMenuMode(), nil
+			return NewMenuMode(), tea.Batch(tea.DisableMouse, tea.ExitAltScreen, m.restoreDeviceState(), stopPreconverting)
+		}
+
+		if m.offeringResume {
+			return m.answerResume(msg)
+		}
+
+		// Skipping works while a file is still loading, so one that can't be played doesn't stop the playlist
+		if m.playlist != nil {
+			switch {
+			case key.Matches(msg, m.keymap.next):
+				if m.playlist.Next() {
+					return m.playItem()
+				}
+				return m, nil
+			case key.Matches(msg, m.keymap.previous):
+				m.playlist.Previous()
+				return m.playItem()
+			case key.Matches(msg, m.keymap.shuffle):
+				m.playlist.SetShuffle(!m.playlist.Shuffled())
+				m.stateMessage = "Shuffle off"
+				if m.playlist.Shuffled() {
+					m.stateMessage = "Shuffle on"
+				}
+				return m, nil
+			case key.Matches(msg, m.keymap.repeat):
+				m.playlist.CycleRepeat()
+				m.stateMessage = "Repeat " + m.playlist.Repeat.String()
+				return m, nil
+			}
+		}
+
+		if m.player == nil {
+			break
 		}
 
 		switch {
 		case key.Matches(msg, m.keymap.reset):
-			return m, m.stopwatch.Reset()
+			m.player.Seek(0)
 		case key.Matches(msg, m.keymap.start, m.keymap.stop):
-			return m, m.stopwatch.Toggle()
+			m.player.Toggle()
 		case key.Matches(msg, m.keymap.skipBackwards):
-			return m, m.stopwatch.Set(m.stopwatch.Elapsed() - time.Second*5)
+			m.player.Seek(m.player.Position() - m.seekStep)
 		case key.Matches(msg, m.keymap.skipForwards):
-			return m, m.stopwatch.Set(m.stopwatch.Elapsed() + time.Second*5)
+			m.player.Seek(m.player.Position() + m.seekStep)
+		case key.Matches(msg, m.keymap.frameBack):
+			m.player.StepFrame(-1)
+		case key.Matches(msg, m.keymap.frameForward):
+			m.player.StepFrame(1)
+		case key.Matches(msg, m.keymap.cycleStep):
+			m = m.setSeekStep(playModeSeekSteps[(slices.Index(playModeSeekSteps, m.seekStep)+1)%len(playModeSeekSteps)])
+			m.stateMessage = fmt.Sprintf("Seeking by %s", m.seekStep)
+		case key.Matches(msg, m.keymap.jump):
+			tenths := time.Duration(msg.String()[0] - '0')
+			m.player.Seek(m.player.Duration() * tenths / 10)
+		case key.Matches(msg, m.keymap.goTo):
+			m.prompting = true
+			m.prompt.SetValue("")
+			focus := m.prompt.Focus()
+			return m.observePlayer(), focus
+		case key.Matches(msg, m.keymap.stats):
+			m.showStats = !m.showStats
+		case key.Matches(msg, m.keymap.adaptive):
+			m.player.SetAdaptive(!m.player.Adaptive())
+			m.stateMessage = "Adaptive frame rate off"
+			if m.player.Adaptive() {
+				m.stateMessage = "Adaptive frame rate on"
+			}
+		case key.Matches(msg, m.keymap.loop):
+			m.player.SetLoop(!m.player.Loop())
+			m.stateMessage = "Loop off"
+			if m.player.Loop() {
+				m.stateMessage = "Loop on"
+			}
+		case key.Matches(msg, m.keymap.abRepeat):
+			m = m.markRepeat()
+		case key.Matches(msg, m.keymap.slower):
+			m = m.stepSpeed(-1)
+		case key.Matches(msg, m.keymap.faster):
+			m = m.stepSpeed(1)
+		case key.Matches(msg, m.keymap.reverse):
+			m.player.SetReverse(!m.player.Reverse())
+			m.stateMessage = "Playing forwards"
+			if m.player.Reverse() {
+				m.stateMessage = "Playing backwards"
+			}
+		case key.Matches(msg, m.keymap.audioEarlier):
+			m = m.shiftAudio(-audioOffsetStep)
+		case key.Matches(msg, m.keymap.audioLater):
+			m = m.shiftAudio(audioOffsetStep)
 		}
 
+		return m.observePlayer(), nil
+
+	case tea.MouseMsg:
+		if m.player == nil || m.prompting || m.offeringResume {
+			return m, nil
+		}
+
+		return m.updateMouse(msg), nil
+
 	case playModeStateMsg:
+		if msg.file.Path != m.file.Path {
+			return m, nil
+		}
+
 		m.state = msg.state
+		m.stateMessage = msg.stateMessage
 		switch msg.state {
 		case playModeLoading:
 			return m, nil
@@ -134,21 +371,53 @@ func (m PlayMode) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		case playModeReady:
 			m.pixelstream = msg.pixelstream
-			m.stopwatch = stopwatch.NewWithInterval(time.Second / time.Duration(m.pixelstream.FrameRate))
-			m.stopwatch.Max = m.pixelstream.GetTotalDuration()
-			return m, m.stopwatch.Init()
+			m.player = NewPlayer(m.pixelstream, output())
+			m = m.observePlayer()
+
+			// Files in a playlist carry on by themselves, so only a file played on its own offers to resume
+			var start tea.Cmd = m.startPlayer
+			if m.playlist == nil {
+				m.resume, m.offeringResume = ResumePosition(m.file)
+			}
+			if m.offeringResume {
+				start = nil
+			}
+
+			return m, tea.Batch(tea.Sequence(saveDeviceState, start), m.loadAudio, m.preconvert())
+		}
+
+	case playModeAudioMsg:
+		if msg.file.Path != m.file.Path {
+			return m, nil
+		}
+
+		if msg.err != nil {
+			m.stateMessage = "No audio: " + msg.err.Error()
+			return m, nil
+		}
+
+		m.audio = msg.audio
+		m.player.SetAudio(m.audio)
+		m.keymap.audioEarlier.SetEnabled(true)
+		m.keymap.audioLater.SetEnabled(true)
+		return m, nil
+
+	case playModeTickMsg:
+		// Ticks meant for the player of a previous file in the playlist are let go
+		if msg.player != m.player {
+			return m, nil
 		}
 
-	case stopwatch.StartStopMsg:
-		m.keymap.stop.SetEnabled(!m.stopwatch.Running())
-		m.keymap.start.SetEnabled(m.stopwatch.Running())
-	case stopwatch.TickMsg:
-		m.frame = m.pixelstream.GetFrame(m.stopwatch.Elapsed())
+		if m.playlist != nil {
+			select {
+			case <-m.player.Ended():
+				return m.playlistEnded()
+			default:
+			}
+		}
 
-		cmd = m.sendFrameLock.TryLock(func() tea.Msg {
-			m.frame.SendFrame(Host + "/api/notify")
-			return nil
-		})
+		m = m.observePlayer()
+		return m, m.tick()
 	}
 
 	var spinnerCmd tea.Cmd
@@ -156,10 +425,239 @@ func (m PlayMode) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.spinner, spinnerCmd = m.spinner.Update(msg)
 	}
 
-	var stopwatchCmd tea.Cmd
-	m.stopwatch, stopwatchCmd = m.stopwatch.Update(msg)
+	return m, spinnerCmd
+}
+
+type playModeTickMsg struct {
+	player *Player
+}
+
+type playModeAudioMsg struct {
+	file  FileLocation
+	audio *ExternalAudio
+	err   error
+}
+
+// loadAudio finds the soundtrack of the file, when audio is turned on. Extracting it from the video can take a moment.
+func (m PlayMode) loadAudio() tea.Msg {
+	if AudioPlayer == "" {
+		return nil
+	}
+
+	fl, err := FindAudio(m.file)
+	if err != nil {
+		return playModeAudioMsg{file: m.file, err: err}
+	}
+
+	audio, err := NewExternalAudio(AudioPlayer, fl, AudioOffset)
+	return playModeAudioMsg{file: m.file, audio: audio, err: err}
+}
+
+// updateMouse seeks with clicks and drags on the progress bar, pauses with clicks on the preview and steps through frames with the wheel.
+func (m PlayMode) updateMouse(msg tea.MouseMsg) PlayMode {
+	position, onBar := m.barPosition(msg.X, msg.Y)
+
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp:
+		m.player.StepFrame(-1)
+	case msg.Button == tea.MouseButtonWheelDown:
+		m.player.StepFrame(1)
+	case msg.Action == tea.MouseActionRelease:
+		m.dragging = false
+	case msg.Button != tea.MouseButtonLeft:
+	case msg.Action == tea.MouseActionPress && onBar:
+		m.dragging = true
+		m.player.Seek(position)
+	case msg.Action == tea.MouseActionMotion && m.dragging:
+		m.player.Seek(position)
+	case msg.Action == tea.MouseActionPress && msg.Y < m.frame.Height && msg.X < m.frame.Width*2:
+		m.player.Toggle()
+	}
+
+	m.hover, m.hovering = position, onBar || m.dragging
+	return m.observePlayer()
+}
+
+// barPosition returns the position under x on the progress bar, and whether x and y are on it.
+// Past the ends of the bar it's the start or end.
+func (m PlayMode) barPosition(x int, y int) (time.Duration, bool) {
+	// The bar starts on the last line of the preview, after the elapsed time
+	row := lipgloss.Height(m.previewView()) - 1
+	start := lipgloss.Width(FmtDuration(m.position)) + 1
+	cell := min(max(x-start, 0), m.progress.Width-1)
+	position := m.player.Duration() * time.Duration(cell) / time.Duration(m.progress.Width-1)
+
+	return position, y == row && x >= start && x < start+m.progress.Width
+}
+
+// answerResume starts playback from where the file was left last time, or from the start.
+func (m PlayMode) answerResume(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "enter":
+		m.player.Seek(m.resume)
+	case "n", "esc":
+	default:
+		return m, nil
+	}
+
+	m.offeringResume = false
+	return m.observePlayer(), m.startPlayer
+}
+
+// setSeekStep changes how far the arrow keys seek, and shows it in their help.
+func (m PlayMode) setSeekStep(step time.Duration) PlayMode {
+	m.seekStep = step
+	m.keymap.skipBackwards.SetHelp("←/j", "back "+step.String())
+	m.keymap.skipForwards.SetHelp("→/l", "forward "+step.String())
+	return m
+}
+
+// updatePrompt passes keys to the go to timestamp prompt, seeking on enter and closing it on enter or esc.
+func (m PlayMode) updatePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.prompting = false
+		m.prompt.Blur()
+		return m, nil
+
+	case "enter":
+		m.prompting = false
+		m.prompt.Blur()
+
+		position, err := ParseTimestamp(m.prompt.Value())
+		if err != nil {
+			m.stateMessage = err.Error()
+			return m, nil
+		}
+
+		if m.player != nil {
+			m.player.Seek(position)
+			m = m.observePlayer()
+		}
+		m.stateMessage = ""
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.prompt, cmd = m.prompt.Update(msg)
+	return m, cmd
+}
+
+// markRepeat sets the A marker, then the B marker that starts the A-B repeat, then turns it off again.
+func (m PlayMode) markRepeat() PlayMode {
+	switch _, _, repeating := m.player.Repeat(); {
+	case repeating:
+		m.player.SetRepeat(0, 0)
+		m.stateMessage = "A-B repeat off"
+	case !m.markingB:
+		m.markA = m.player.Position()
+		m.markingB = true
+		m.stateMessage = fmt.Sprintf("A set at %s, press b again to set B", FmtDuration(m.markA))
+	default:
+		m.player.SetRepeat(m.markA, m.player.Position())
+		m.markingB = false
+		m.stateMessage = ""
+		if _, _, repeating := m.player.Repeat(); !repeating {
+			m.stateMessage = "A and B can't be at the same place"
+		}
+	}
+
+	return m
+}
+
+// stepSpeed moves steps through playModeSpeeds from the current speed.
+func (m PlayMode) stepSpeed(steps int) PlayMode {
+	i := slices.Index(playModeSpeeds, m.player.Speed()) + steps
+	m.player.SetSpeed(playModeSpeeds[min(max(i, 0), len(playModeSpeeds)-1)])
+	m.stateMessage = fmt.Sprintf("Speed %gx", m.player.Speed())
+	return m
+}
+
+// shiftAudio moves the audio by d relative to the LEDs.
+func (m PlayMode) shiftAudio(d time.Duration) PlayMode {
+	m.audio.SetOffset(m.audio.Offset() + d)
+	m.player.SyncAudio()
+	m.stateMessage = fmt.Sprintf("Audio offset %s", m.audio.Offset())
+	return m
+}
+
+// tick refreshes the view at the stream's frame rate. Playback itself is timed by the player, not by these ticks.
+func (m PlayMode) tick() tea.Cmd {
+	return tea.Tick(time.Second/time.Duration(m.pixelstream.FrameRate), func(_ time.Time) tea.Msg {
+		return playModeTickMsg{player: m.player}
+	})
+}
+
+func (m PlayMode) startPlayer() tea.Msg {
+	m.player.Play()
+	return playModeTickMsg{player: m.player}
+}
+
+// playlistEnded plays the file again when repeating one, and otherwise moves on to the next file if there is one.
+func (m PlayMode) playlistEnded() (tea.Model, tea.Cmd) {
+	if m.playlist.Repeat == RepeatOne {
+		m.player.Play()
+		return m.observePlayer(), m.tick()
+	}
+
+	if m.playlist.Next() {
+		return m.playItem()
+	}
+
+	// The view keeps refreshing, so playing the last file again from here shows and moves on as usual
+	return m.observePlayer(), m.tick()
+}
+
+// playItem switches to the playlist's current file. The clock state saved for the first file is kept until the playlist is quit.
+func (m PlayMode) playItem() (tea.Model, tea.Cmd) {
+	next := NewPlaylistMode(m.playlist)
+	next.showStats = m.showStats
+
+	mode, cmd := SwitchMode(next)
+	return mode, tea.Sequence(m.closePlayer, cmd)
+}
+
+// preconvert converts the rest of the playlist in the background, so it's ready by the time it's reached.
+func (m PlayMode) preconvert() tea.Cmd {
+	if m.playlist == nil {
+		return nil
+	}
+
+	upcoming := m.playlist.Upcoming()
+	return func() tea.Msg {
+		Preconvert(upcoming, Device.Width, Device.Height)
+		return nil
+	}
+}
+
+// stopPreconverting stops converting the rest of a playlist once it's left.
+func stopPreconverting() tea.Msg {
+	StopPreconverting()
+	return nil
+}
+
+// observePlayer copies what the player is doing into the model, for the view.
+func (m PlayMode) observePlayer() PlayMode {
+	m.position = m.player.Position()
+	m.frame = m.player.Frame()
+	m.frameIndex = m.player.FrameIndex()
 
-	return m, tea.Batch(cmd, spinnerCmd, stopwatchCmd)
+	playing := m.player.Playing()
+	m.keymap.start.SetEnabled(!playing)
+	m.keymap.stop.SetEnabled(playing)
+
+	// Working out the latency stats sorts the recent latencies, so they're only fetched while they're shown
+	if m.showStats {
+		m.stats = m.player.Stats()
+	}
+
+	// The audio is started in the background, so a player that fails to start is only found out here
+	if err := m.player.AudioErr(); err != nil && err != m.audioErr {
+		m.stateMessage = "Audio failed: " + err.Error()
+	}
+	m.audioErr = m.player.AudioErr()
+
+	return m
 }
 
 func (m PlayMode) View() string {
@@ -179,19 +677,42 @@ func (m PlayMode) View() string {
 		s.WriteString(m.file.Path)
 		s.WriteRune('\n')
 	case playModeReady:
-		s.WriteString(m.frame.View())
-		s.WriteRune('\n')
+		s.WriteString(m.previewView())
 
-		s.WriteString(FmtDuration(m.stopwatch.Elapsed()))
+		s.WriteString(FmtDuration(m.position))
 		s.WriteRune(' ')
-		s.WriteString(m.progress.ViewAs(float64(m.stopwatch.Elapsed()) / float64(m.stopwatch.Max)))
+		s.WriteString(m.progress.ViewAs(float64(m.position) / float64(m.player.Duration())))
 		s.WriteRune(' ')
-		s.WriteString(FmtDuration(m.stopwatch.Max))
+		s.WriteString(FmtDuration(m.player.Duration()))
+		s.WriteString(fmt.Sprintf("  Frame %d/%d", m.frameIndex+1, m.player.FrameCount()))
+		if m.hovering {
+			s.WriteString("  Seek to ")
+			s.WriteString(FmtDuration(m.hover))
+		}
 
 		s.WriteRune('\n')
+
+		if options := m.optionsView(); options != "" {
+			s.WriteString(options)
+			s.WriteRune('\n')
+		}
+
+		if m.showStats {
+			s.WriteString(m.stats.String())
+			s.WriteRune('\n')
+		}
 	}
 
-	if m.stateMessage != "" {
+	if m.playlist != nil {
+		s.WriteString(m.playlistView())
+	}
+
+	if m.prompting {
+		s.WriteString(m.prompt.View())
+		s.WriteRune('\n')
+	} else if m.offeringResume {
+		s.WriteString(fmt.Sprintf("Resume from %s? [y/n]\n", FmtDuration(m.resume)))
+	} else if m.stateMessage != "" {
 		s.WriteString(m.stateMessage)
 		s.WriteRune('\n')
 	}
@@ -205,8 +726,44 @@ func (m PlayMode) View() string {
 	return s.String()
 }
 
+// previewView is the frame being played followed by a blank line, which the view starts with.
+func (m PlayMode) previewView() string {
+	return m.frame.View() + "\n"
+}
+
+// optionsView lists the playback options that aren't at their defaults.
+func (m PlayMode) optionsView() string {
+	var options []string
+	if speed := m.player.Speed(); speed != 1 {
+		options = append(options, fmt.Sprintf("Speed %gx", speed))
+	}
+	if m.player.Reverse() {
+		options = append(options, "Reverse")
+	}
+	if m.player.Loop() {
+		options = append(options, "Loop")
+	}
+	if from, to, repeating := m.player.Repeat(); repeating {
+		options = append(options, fmt.Sprintf("A-B %s-%s", FmtDuration(from), FmtDuration(to)))
+	} else if m.markingB {
+		options = append(options, fmt.Sprintf("A %s", FmtDuration(m.markA)))
+	}
+
+	return strings.Join(options, "  ")
+}
+
+func (m PlayMode) playlistView() string {
+	shuffle := "off"
+	if m.playlist.Shuffled() {
+		shuffle = "on"
+	}
+
+	return fmt.Sprintf("%d/%d %s  Shuffle %s  Repeat %s\n", m.playlist.Position()+1, m.playlist.Len(), path.Base(m.file.Path), shuffle, m.playlist.Repeat)
+}
+
 func (m PlayMode) helpViewQuitOnly() string {
 	return "\n" + m.help.ShortHelpView([]key.Binding{
+		m.keymap.next,
 		m.keymap.quit,
 	})
 }
@@ -219,10 +776,25 @@ func (m PlayMode) helpView() string {
 		m.keymap.quit,
 		m.keymap.skipBackwards,
 		m.keymap.skipForwards,
+		m.keymap.cycleStep,
+		m.keymap.frameBack,
+		m.keymap.jump,
+		m.keymap.goTo,
+		m.keymap.stats,
+		m.keymap.adaptive,
+		m.keymap.loop,
+		m.keymap.abRepeat,
+		m.keymap.slower,
+		m.keymap.reverse,
+		m.keymap.audioEarlier,
+		m.keymap.next,
+		m.keymap.shuffle,
+		m.keymap.repeat,
 	})
 }
 
 type playModeStateMsg struct {
+	file         FileLocation
 	state        playModeState
 	stateMessage string
 	pixelstream  *PixelStream
@@ -231,12 +803,12 @@ type playModeStateMsg struct {
 func (m PlayMode) LoadFile() tea.Cmd {
 	return tea.Sequence(
 		m.spinner.Tick,
-		func() tea.Msg {
+		m.forFile(func() playModeStateMsg {
 			return playModeStateMsg{
 				state: playModeLoading,
 			}
-		},
-		func() tea.Msg {
+		}),
+		m.forFile(func() playModeStateMsg {
 			var pixelstream *PixelStream
 			var err error
 			if strings.HasSuffix(m.file.Path, pixelstreamFileExt) {
@@ -264,30 +836,63 @@ func (m PlayMode) LoadFile() tea.Cmd {
 				}
 			}
 
-			return playModeStateMsg{
-				state:       playModeReady,
-				pixelstream: pixelstream,
-			}
-		},
+			return fitToDevice(pixelstream)
+		}),
 	)
 }
 
-func (m PlayMode) GenerateFile() tea.Cmd {
-	return func() tea.Msg {
-		var pixelstream *PixelStream
-		var err error
-		pixelstream, err = GeneratePixelStream(m.file, defaultFrameRate)
-		if err != nil {
-			return playModeStateMsg{
-				state:        playModeError,
-				stateMessage: err.Error(),
-			}
+// fitToDevice rescales a stream made for a different matrix size to the connected clock.
+func fitToDevice(pixelstream *PixelStream) playModeStateMsg {
+	if pixelstream.Width == Device.Width && pixelstream.Height == Device.Height {
+		return playModeStateMsg{
+			state:       playModeReady,
+			pixelstream: pixelstream,
 		}
+	}
+
+	return playModeStateMsg{
+		state:        playModeReady,
+		stateMessage: fmt.Sprintf("Rescaled from %dx%d to the clock's %s", pixelstream.Width, pixelstream.Height, Device),
+		pixelstream:  pixelstream.Resize(Device.Width, Device.Height),
+	}
+}
 
-		err = pixelstream.SaveFile(FileLocation{
-			System: m.file.System,
-			Path:   m.file.Path + pixelstreamFileExt,
-		})
+func saveDeviceState() tea.Msg {
+	SaveDeviceState()
+	return nil
+}
+
+func restoreDeviceState() tea.Msg {
+	RestoreDeviceState()
+	return nil
+}
+
+// closePlayer stops playback and remembers where it got to. An offer to resume that was never answered keeps the position from before.
+func (m PlayMode) closePlayer() tea.Msg {
+	if m.player != nil {
+		position := m.player.Position()
+		m.player.Close()
+
+		if !m.offeringResume {
+			SavePosition(m.file, position, m.player.Duration())
+		}
+	}
+	return nil
+}
+
+// Close stops the player, for when the program is quit without going through Update, such as by a signal.
+func (m PlayMode) Close() {
+	m.closePlayer()
+}
+
+// Stop the player first, so a frame that's being sent can't reappear after the notification is dismissed
+func (m PlayMode) restoreDeviceState() tea.Cmd {
+	return tea.Sequence(m.closePlayer, restoreDeviceState)
+}
+
+func (m PlayMode) GenerateFile() tea.Cmd {
+	return m.forFile(func() playModeStateMsg {
+		pixelstream, err := ConvertFile(context.Background(), m.file, Device.Width, Device.Height, false)
 		if err != nil {
 			return playModeStateMsg{
 				state:        playModeError,
@@ -299,5 +904,14 @@ func (m PlayMode) GenerateFile() tea.Cmd {
 			state:       playModeReady,
 			pixelstream: pixelstream,
 		}
+	})
+}
+
+// forFile marks the message of cmd as being about m's file, so it's ignored if it arrives once a playlist has moved on.
+func (m PlayMode) forFile(cmd func() playModeStateMsg) tea.Cmd {
+	return func() tea.Msg {
+		msg := cmd()
+		msg.file = m.file
+		return msg
 	}
 }
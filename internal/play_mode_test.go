@@ -0,0 +1,533 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// loadPlayMode runs PlayMode's loading commands for fl and returns the model along with the messages that start playback.
+func loadPlayMode(t *testing.T, fl FileLocation) (PlayMode, []tea.Msg) {
+	t.Helper()
+
+	m := NewPlayMode(fl)
+	model, cmds := update(m, runCmd(m.Init())...)
+
+	var msgs []tea.Msg
+	for _, cmd := range cmds {
+		msgs = append(msgs, runCmd(cmd)...)
+	}
+
+	return model.(PlayMode), msgs
+}
+
+// startPlayMode loads ps and starts playback. The player is closed when the test ends.
+func startPlayMode(t *testing.T, ps *PixelStream) PlayMode {
+	t.Helper()
+
+	m, msgs := loadPlayMode(t, saveTestStream(t, ps))
+	if m.state != playModeReady {
+		t.Fatalf("expected ready state, got %d: %s", m.state, m.stateMessage)
+	}
+	t.Cleanup(m.player.Close)
+
+	// The view refresh ticks that follow are left out, the player runs on its own
+	model, _ := update(m, msgs...)
+	m = model.(PlayMode)
+
+	if !m.player.Playing() {
+		t.Fatal("expected playback to start once loaded")
+	}
+
+	return m
+}
+
+// waitForEnd waits for the player to reach the end of the stream.
+func waitForEnd(t *testing.T, player *Player) {
+	t.Helper()
+
+	select {
+	case <-player.Ended():
+	case <-time.After(time.Second * 5):
+		t.Fatal("expected playback to end")
+	}
+}
+
+func TestPlayModeLoadsFile(t *testing.T) {
+	newTestClock(t)
+
+	ps := newTestStream(32, 16)
+	m := startPlayMode(t, ps)
+
+	if len(m.pixelstream.Frames) != len(ps.Frames) {
+		t.Errorf("expected %d frames, got %d", len(ps.Frames), len(m.pixelstream.Frames))
+	}
+
+	if m.player.Duration() != time.Second*2 {
+		t.Errorf("expected a duration of 2s, got %s", m.player.Duration())
+	}
+
+	view := m.View()
+	if !strings.Contains(view, "00:00:00") || !strings.Contains(view, "00:00:02") {
+		t.Errorf("expected elapsed and total time in view, got:\n%s", view)
+	}
+}
+
+func TestPlayModeStreamsFramesToClock(t *testing.T) {
+	clock := newTestClock(t)
+
+	ps := newTestStream(16, 64)
+	m := startPlayMode(t, ps)
+
+	waitForEnd(t, m.player)
+
+	received := clock.ReceivedFrames()
+	if len(received) < 2 {
+		t.Fatalf("expected frames to reach the clock, got %d", len(received))
+	}
+
+	for i := 1; i < len(received); i++ {
+		if received[i].Pixels[0][0] <= received[i-1].Pixels[0][0] {
+			t.Errorf("expected frames to arrive in order, got frame %d after frame %d", received[i].Pixels[0][0], received[i-1].Pixels[0][0])
+		}
+	}
+
+	if screen := clock.Screen(); !screen.Equal(&ps.Frames[15]) {
+		t.Errorf("expected the clock to display the last frame, got frame %d", screen.Pixels[0][0])
+	}
+
+	model, _ := m.Update(playModeTickMsg{player: m.player})
+	m = model.(PlayMode)
+	if m.position != m.player.Duration() || !m.keymap.start.Enabled() {
+		t.Errorf("expected the view to show playback stopped at the end, got %s", m.position)
+	}
+}
+
+func TestPlayModeSeek(t *testing.T) {
+	newTestClock(t)
+
+	m := startPlayMode(t, newTestStream(16*60, 16))
+
+	press := func(key string) {
+		t.Helper()
+		model, cmd := m.Update(keyPress(key))
+		model, _ = update(model, runCmd(cmd)...)
+		m = model.(PlayMode)
+	}
+
+	// Paused, so the position only changes by seeking
+	press(" ")
+	press("r")
+	if m.position != 0 {
+		t.Errorf("expected reset to seek to 0, got %s", m.position)
+	}
+
+	press("right")
+	press("l")
+	if m.position != time.Second*10 {
+		t.Errorf("expected to seek forwards to 10s, got %s", m.position)
+	}
+
+	press("left")
+	if m.position != time.Second*5 {
+		t.Errorf("expected to seek backwards to 5s, got %s", m.position)
+	}
+
+	press("j")
+	press("j")
+	if m.position != 0 {
+		t.Errorf("expected seeking before the start to clamp to 0, got %s", m.position)
+	}
+
+	for i := 0; i < 20; i++ {
+		press("right")
+	}
+	if m.position != time.Minute {
+		t.Errorf("expected seeking past the end to clamp to 1m, got %s", m.position)
+	}
+}
+
+func TestPlayModePause(t *testing.T) {
+	clock := newTestClock(t)
+
+	m := startPlayMode(t, newTestStream(32, 16))
+
+	model, cmd := m.Update(keyPress(" "))
+	model, _ = update(model, runCmd(cmd)...)
+	m = model.(PlayMode)
+
+	if m.player.Playing() || m.keymap.stop.Enabled() {
+		t.Fatal("expected space to pause playback")
+	}
+
+	position := m.player.Position()
+	received := clock.ReceivedFrameCount()
+	time.Sleep(time.Millisecond * 200)
+
+	if m.player.Position() != position {
+		t.Errorf("expected time not to advance while paused, went from %s to %s", position, m.player.Position())
+	}
+
+	if clock.ReceivedFrameCount() != received {
+		t.Errorf("expected no frames to be sent while paused, got %d more", clock.ReceivedFrameCount()-received)
+	}
+}
+
+func TestPlayModeInvalidFile(t *testing.T) {
+	newTestClock(t)
+
+	for name, contents := range map[string]string{
+		"corrupt": "NOTAPIXELSTREAMFILE",
+		"short":   "PX",
+		"version": pixelstreamFormatIdentifier + "\xff\x10",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path, err := filepath.Abs(filepath.Join(t.TempDir(), name+pixelstreamFileExt))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = os.WriteFile(path, []byte(contents), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m, _ := loadPlayMode(t, FromOSPath(path))
+			if m.state != playModeError {
+				t.Fatalf("expected error state, got %d", m.state)
+			}
+
+			view := m.View()
+			if !strings.Contains(view, "Error processing file") || !strings.Contains(view, m.stateMessage) || m.stateMessage == "" {
+				t.Errorf("expected the error to be shown, got:\n%s", view)
+			}
+		})
+	}
+}
+
+func TestPlayModeQuitRestoresClock(t *testing.T) {
+	clock := newTestClock(t)
+
+	m := startPlayMode(t, newTestStream(32, 16))
+
+	err := SetDeviceSettings(map[string]any{"BRI": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SwitchApp("Date")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model, cmd := m.Update(keyPress("q"))
+	if _, ok := model.(MenuMode); !ok {
+		t.Fatalf("expected q to return to the menu, got %T", model)
+	}
+	runCmd(cmd)
+
+	if clock.NotificationActive() {
+		t.Error("expected the stream to be dismissed")
+	}
+
+	if clock.Settings()["BRI"] != float64(100) {
+		t.Errorf("expected brightness to be restored to 100, got %v", clock.Settings()["BRI"])
+	}
+
+	if clock.App() != "Time" {
+		t.Errorf("expected the Time app to be restored, got %s", clock.App())
+	}
+}
+
+func TestPlayModeCloseStopsStreaming(t *testing.T) {
+	clock := newTestClock(t)
+
+	m := startPlayMode(t, newTestStream(16*60, 16))
+	m.Close()
+
+	received := clock.ReceivedFrameCount()
+	time.Sleep(time.Millisecond * 200)
+	if clock.ReceivedFrameCount() != received {
+		t.Error("expected no more frames to be sent once closed")
+	}
+}
+
+func TestPlayModeRescalesToDevice(t *testing.T) {
+	clock := newTestClockWithSize(t, 64, 8)
+
+	device, err := ProbeDevice(Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device != (DeviceInfo{Width: 64, Height: 8}) {
+		t.Fatalf("expected to probe a 64x8 clock, got %s", device)
+	}
+	Device = device
+
+	m := startPlayMode(t, newTestStream(32, 16))
+	if m.pixelstream.Width != 64 || m.pixelstream.Height != 8 {
+		t.Fatalf("expected the stream to be rescaled to 64x8, got %dx%d", m.pixelstream.Width, m.pixelstream.Height)
+	}
+	if !strings.Contains(m.View(), "Rescaled from 32x8") {
+		t.Errorf("expected the rescale to be shown, got:\n%s", m.View())
+	}
+
+	// Paused straight away so the frame on the clock is known
+	m.player.Pause()
+	m.player.Seek(0)
+	time.Sleep(time.Millisecond * 100)
+
+	screen := clock.Screen()
+	if screen.Width != 64 || screen.Pixels[1] != [3]uint8{0, 0, 255} {
+		t.Errorf("expected the first pixel to be doubled across the wider clock, got %v", screen.Pixels[:2])
+	}
+}
+
+func TestPlayModeLoadsVersion1File(t *testing.T) {
+	newTestClock(t)
+
+	// Version 1 files have no size in the header and are always 32x8
+	contents := []byte(pixelstreamFormatIdentifier + "\x01\x10")
+	for i := 0; i < 16; i++ {
+		frame := make([]byte, DefaultFrameWidth*DefaultFrameHeight*3)
+		frame[0] = byte(i)
+		contents = append(contents, frame...)
+	}
+
+	path, err := filepath.Abs(filepath.Join(t.TempDir(), "v1"+pixelstreamFileExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(path, contents, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, _ := loadPlayMode(t, FromOSPath(path))
+	if m.state != playModeReady {
+		t.Fatalf("expected ready state, got %d: %s", m.state, m.stateMessage)
+	}
+
+	if len(m.pixelstream.Frames) != 16 || m.pixelstream.Frames[15].Pixels[0][0] != 15 {
+		t.Errorf("expected 16 32x8 frames, got %d", len(m.pixelstream.Frames))
+	}
+}
+
+func TestPlayModeStatsOverlay(t *testing.T) {
+	newTestClock(t)
+
+	m := startPlayMode(t, newTestStream(16, 64))
+	waitForEnd(t, m.player)
+
+	if strings.Contains(m.View(), "Dropped") {
+		t.Error("expected the stats to be hidden until toggled")
+	}
+
+	model, _ := m.Update(keyPress("s"))
+	m = model.(PlayMode)
+
+	if m.stats.Sent == 0 || !strings.Contains(m.View(), m.stats.String()) {
+		t.Errorf("expected the stats to be shown, got:\n%s", m.View())
+	}
+}
+
+func TestPlayModePlaybackOptions(t *testing.T) {
+	newTestClock(t)
+
+	m := startPlayMode(t, newTestStream(16*60, 16))
+
+	press := func(key string) {
+		t.Helper()
+		model, cmd := m.Update(keyPress(key))
+		model, _ = update(model, runCmd(cmd)...)
+		m = model.(PlayMode)
+	}
+
+	// Paused, so the position only changes by seeking
+	press(" ")
+	press("r")
+	press("o")
+	if !m.player.Loop() || !strings.Contains(m.View(), "Loop") {
+		t.Error("expected o to turn loop on")
+	}
+
+	// A at 10s, B at 20s
+	press("l")
+	press("l")
+	press("b")
+	press("l")
+	press("l")
+	press("b")
+	if from, to, repeating := m.player.Repeat(); !repeating || from != time.Second*10 || to != time.Second*20 {
+		t.Errorf("expected A-B repeat from 10s to 20s, got %s-%s", from, to)
+	}
+	if !strings.Contains(m.View(), "A-B 00:00:10-00:00:20") {
+		t.Errorf("expected the view to show the A-B repeat, got:\n%s", m.View())
+	}
+
+	press("b")
+	if _, _, repeating := m.player.Repeat(); repeating {
+		t.Error("expected a third b to turn A-B repeat off")
+	}
+
+	press("+")
+	press("=")
+	if m.player.Speed() != 1.5 {
+		t.Errorf("expected two steps up to 1.5x, got %gx", m.player.Speed())
+	}
+
+	for i := 0; i < 10; i++ {
+		press("-")
+	}
+	if m.player.Speed() != MinSpeed || !strings.Contains(m.View(), "Speed 0.25x") {
+		t.Errorf("expected the speed to stop at 0.25x, got %gx", m.player.Speed())
+	}
+
+	press("v")
+	if !m.player.Reverse() || !strings.Contains(m.View(), "Reverse") {
+		t.Error("expected v to play backwards")
+	}
+}
+
+func TestPlayModeFramesAndJumps(t *testing.T) {
+	newTestClock(t)
+
+	m := startPlayMode(t, newTestStream(16*60, 16))
+
+	press := func(key string) {
+		t.Helper()
+		model, cmd := m.Update(keyPress(key))
+		model, _ = update(model, runCmd(cmd)...)
+		m = model.(PlayMode)
+	}
+
+	press("5")
+	if m.player.Position() < time.Second*30 || m.player.Position() > time.Second*31 {
+		t.Errorf("expected 5 to jump to half way, got %s", m.player.Position())
+	}
+
+	press(" ")
+	press("0")
+	press(".")
+	press(".")
+	press(",")
+	if m.player.Playing() || m.player.FrameIndex() != 1 {
+		t.Errorf("expected to step paused to frame 1, got %d", m.player.FrameIndex())
+	}
+	if !strings.Contains(m.View(), "Frame 2/960") {
+		t.Errorf("expected the view to show the frame number, got:\n%s", m.View())
+	}
+
+	press(",")
+	press(",")
+	if m.player.FrameIndex() != 0 {
+		t.Errorf("expected stepping back to stop at the first frame, got %d", m.player.FrameIndex())
+	}
+
+	press("t")
+	press("l")
+	if m.seekStep != time.Second*10 || m.player.Position() != time.Second*10 {
+		t.Errorf("expected t to change the seek step to 10s, got %s", m.player.Position())
+	}
+
+	press("g")
+	for _, r := range "0:42.5" {
+		press(string(r))
+	}
+	if !strings.Contains(m.View(), "Go to: 0:42.5") {
+		t.Errorf("expected the prompt to take the keys, got:\n%s", m.View())
+	}
+	press("enter")
+	if m.prompting || m.player.Position() != time.Millisecond*42500 {
+		t.Errorf("expected to go to 42.5s, got %s", m.player.Position())
+	}
+
+	press("g")
+	press("x")
+	press("enter")
+	if !strings.Contains(m.View(), "invalid timestamp") || m.player.Position() != time.Millisecond*42500 {
+		t.Errorf("expected a bad timestamp to be shown and not seek, got:\n%s", m.View())
+	}
+}
+
+func TestPlayModeMouse(t *testing.T) {
+	newTestClock(t)
+
+	m := startPlayMode(t, newTestStream(16*60, 16))
+
+	mouse := func(x int, y int, button tea.MouseButton, action tea.MouseAction) {
+		t.Helper()
+		model, _ := m.Update(tea.MouseMsg{X: x, Y: y, Button: button, Action: action})
+		m = model.(PlayMode)
+	}
+
+	// The bar is on the line with the elapsed time, 46 cells from after "00:00:00 "
+	barY := slices.IndexFunc(strings.Split(m.View(), "\n"), func(line string) bool {
+		return strings.HasPrefix(line, "00:00:00 ")
+	})
+	if barY != m.frame.Height+1 {
+		t.Fatalf("expected the bar under the preview and a blank line, got line %d of:\n%s", barY, m.View())
+	}
+	barX := func(cell int) int { return 9 + cell }
+
+	mouse(0, 0, tea.MouseButtonLeft, tea.MouseActionPress)
+	mouse(0, 0, tea.MouseButtonLeft, tea.MouseActionRelease)
+	if m.player.Playing() {
+		t.Fatal("expected clicking the preview to pause")
+	}
+
+	paused := m.player.Position()
+	mouse(barX(9), barY-1, tea.MouseButtonLeft, tea.MouseActionPress)
+	mouse(barX(9), barY-1, tea.MouseButtonLeft, tea.MouseActionRelease)
+	if m.player.Position() != paused {
+		t.Errorf("expected clicking the blank line above the bar to do nothing, got %s", m.player.Position())
+	}
+
+	mouse(barX(9), barY, tea.MouseButtonLeft, tea.MouseActionPress)
+	if m.player.Position() != time.Second*12 {
+		t.Errorf("expected clicking a fifth of the way along the bar to seek to 12s, got %s", m.player.Position())
+	}
+
+	// Dragging carries on past the ends of the bar
+	mouse(barX(18), barY, tea.MouseButtonLeft, tea.MouseActionMotion)
+	if m.player.Position() != time.Second*24 {
+		t.Errorf("expected dragging to seek to 24s, got %s", m.player.Position())
+	}
+	mouse(200, barY+3, tea.MouseButtonLeft, tea.MouseActionMotion)
+	if m.player.Position() != time.Minute {
+		t.Errorf("expected dragging past the bar to seek to the end, got %s", m.player.Position())
+	}
+	mouse(barX(18), barY, tea.MouseButtonLeft, tea.MouseActionMotion)
+	mouse(barX(18), barY, tea.MouseButtonLeft, tea.MouseActionRelease)
+
+	mouse(barX(36), barY, tea.MouseButtonNone, tea.MouseActionMotion)
+	if m.player.Position() != time.Second*24 || !strings.Contains(m.View(), "Seek to 00:00:48") {
+		t.Errorf("expected hovering to show the timestamp without seeking, got:\n%s", m.View())
+	}
+
+	mouse(0, barY+1, tea.MouseButtonNone, tea.MouseActionMotion)
+	if strings.Contains(m.View(), "Seek to") {
+		t.Error("expected the timestamp to go once the mouse leaves the bar")
+	}
+
+	mouse(0, 0, tea.MouseButtonWheelDown, tea.MouseActionPress)
+	if m.player.FrameIndex() != 24*16+1 {
+		t.Errorf("expected scrolling down to step a frame forward, got %d", m.player.FrameIndex())
+	}
+	mouse(0, 0, tea.MouseButtonWheelUp, tea.MouseActionPress)
+	mouse(0, 0, tea.MouseButtonWheelUp, tea.MouseActionPress)
+	if m.player.FrameIndex() != 24*16-1 {
+		t.Errorf("expected scrolling up to step frames back, got %d", m.player.FrameIndex())
+	}
+
+	mouse(0, 0, tea.MouseButtonLeft, tea.MouseActionPress)
+	if !m.player.Playing() {
+		t.Error("expected clicking the preview again to play")
+	}
+}
@@ -0,0 +1,719 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// PlaybackStats counts what happened to the frames of a playback.
+type PlaybackStats struct {
+	Sent int
+	// Frames that came due while an earlier frame was still being sent, so were never sent
+	Dropped int
+	Failed  int
+	// How long frames took to send, over the last latencyWindow frames sent, or every frame for TotalStats
+	AverageLatency time.Duration
+	P95Latency     time.Duration
+	// Frames sent per second, which is below the stream's frame rate when it's adapting to a slow link
+	FrameRate int
+}
+
+func (s PlaybackStats) String() string {
+	return fmt.Sprintf("Sent %d  Dropped %d  Failed %d  Latency avg %s  p95 %s  Rate %d fps",
+		s.Sent, s.Dropped, s.Failed, s.AverageLatency.Round(time.Millisecond), s.P95Latency.Round(time.Millisecond), s.FrameRate)
+}
+
+// The latency stats cover this many of the most recent frames, so they cost the same however long the video is
+const latencyWindow = 512
+
+// latencyRing keeps how long the most recent frames took to send.
+type latencyRing struct {
+	latencies [latencyWindow]time.Duration
+	next      int
+	count     int
+}
+
+func (r *latencyRing) add(latency time.Duration) {
+	r.latencies[r.next] = latency
+	r.next = (r.next + 1) % latencyWindow
+	r.count = min(r.count+1, latencyWindow)
+}
+
+// summary returns the average and 95th percentile of the latencies kept.
+func (r *latencyRing) summary() (average time.Duration, p95 time.Duration) {
+	if r.count == 0 {
+		return 0, 0
+	}
+
+	sorted := slices.Clone(r.latencies[:r.count])
+	slices.Sort(sorted)
+
+	var total time.Duration
+	for _, latency := range sorted {
+		total += latency
+	}
+
+	return total / time.Duration(r.count), sorted[(r.count*95+99)/100-1]
+}
+
+// The whole-run p95 is estimated from a histogram of latencies to the millisecond, with anything slower in the last bucket
+const latencyHistogramSize = 2000
+
+// latencyTotals keeps enough about every frame sent to summarise a whole playback, without keeping every latency.
+type latencyTotals struct {
+	count     int
+	total     time.Duration
+	longest   time.Duration
+	histogram [latencyHistogramSize]int
+}
+
+func (t *latencyTotals) add(latency time.Duration) {
+	t.count++
+	t.total += latency
+	t.longest = max(t.longest, latency)
+	t.histogram[min(int(latency/time.Millisecond), latencyHistogramSize-1)]++
+}
+
+// summary returns the average and an estimate of the 95th percentile, rounded up to the millisecond.
+func (t *latencyTotals) summary() (average time.Duration, p95 time.Duration) {
+	if t.count == 0 {
+		return 0, 0
+	}
+
+	average = t.total / time.Duration(t.count)
+	rank := (t.count*95 + 99) / 100
+	seen := 0
+	for i, count := range t.histogram {
+		seen += count
+		if seen < rank {
+			continue
+		}
+
+		// The last bucket holds everything slower, so it has no upper bound
+		if i == latencyHistogramSize-1 {
+			return average, t.longest
+		}
+		return average, min(time.Duration(i+1)*time.Millisecond, t.longest)
+	}
+
+	return average, t.longest
+}
+
+// Player plays a PixelStream to a Transport in real time. The position is worked out from the monotonic clock
+// instead of by counting ticks, so a slow send drops the frames that came due meanwhile rather than slowing playback down.
+type Player struct {
+	stream *PixelStream
+	output Transport
+	now    func() time.Time
+
+	mutex   sync.Mutex
+	playing bool
+	// The position when playback last started or was seeked, and the position itself while paused
+	offset  time.Duration
+	started time.Time
+	// The frame last sent, and the slot of the send grid it was sent in. Both are -1 before the first frame.
+	index     int
+	slot      int
+	resend    bool
+	stats     PlaybackStats
+	latencies latencyRing
+	totals    latencyTotals
+
+	// In adaptive mode frames are sent at rate frames per second, which follows how quickly the output takes them
+	adaptive       bool
+	rate           int
+	latency        time.Duration
+	lastRateChange time.Time
+
+	// Follows play, pause and seek when set
+	audio *audioFollower
+
+	// The speed in quarters, so 4 is normal speed
+	speed   int
+	reverse bool
+	loop    bool
+	// A-B repeat plays from repeatFrom to repeatTo over and over, while repeatTo is after repeatFrom
+	repeatFrom time.Duration
+	repeatTo   time.Duration
+
+	wake   chan struct{}
+	ended  chan struct{}
+	closed chan struct{}
+	done   chan struct{}
+	close  sync.Once
+}
+
+// AdaptiveFrameRate makes new players start with the adaptive frame rate turned on.
+var AdaptiveFrameRate bool
+
+// NewPlayer starts a paused player for stream.
+func NewPlayer(stream *PixelStream, output Transport) *Player {
+	p := newPlayer(stream, output, time.Now)
+	p.SetAdaptive(AdaptiveFrameRate)
+	go p.run()
+
+	return p
+}
+
+func newPlayer(stream *PixelStream, output Transport, now func() time.Time) *Player {
+	return &Player{
+		stream: stream,
+		output: output,
+		now:    now,
+		index:  -1,
+		slot:   -1,
+		rate:   int(stream.FrameRate),
+		speed:  4,
+		wake:   make(chan struct{}, 1),
+		ended:  make(chan struct{}, 1),
+		closed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+func (p *Player) Play() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.playing {
+		return
+	}
+
+	// Playing again once the end is reached starts over
+	if !p.reverse && p.offset >= p.Duration() {
+		p.offset = 0
+	} else if p.reverse && p.offset <= 0 {
+		p.offset = p.Duration()
+	}
+
+	p.playing = true
+	p.started = p.now()
+	p.playAudio()
+	p.notify()
+}
+
+func (p *Player) Pause() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.offset = p.position()
+	p.playing = false
+	if p.audio != nil {
+		p.audio.pause()
+	}
+	p.notify()
+}
+
+func (p *Player) Toggle() {
+	if p.Playing() {
+		p.Pause()
+	} else {
+		p.Play()
+	}
+}
+
+// Seek moves playback to d, clamped to the stream, and sends the frame there straight away.
+func (p *Player) Seek(d time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.offset = min(max(d, 0), p.Duration())
+	p.started = p.now()
+	p.resend = true
+	if p.playing {
+		p.playAudio()
+	}
+	p.notify()
+}
+
+// StepFrame pauses and moves frames whole frames forwards, or backwards if negative, to the start of that frame.
+func (p *Player) StepFrame(frames int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	index := min(max(p.stream.frameIndex(p.position())+frames, 0), len(p.stream.Frames)-1)
+	p.offset = p.stream.frameTime(index)
+	p.playing = false
+	p.resend = true
+	if p.audio != nil {
+		p.audio.pause()
+	}
+	p.notify()
+}
+
+func (p *Player) Position() time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.position()
+}
+
+func (p *Player) Duration() time.Duration {
+	return p.stream.GetTotalDuration()
+}
+
+func (p *Player) Playing() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.playing
+}
+
+// Frame returns the frame at the current position.
+func (p *Player) Frame() *Frame {
+	return p.stream.GetFrame(p.Position())
+}
+
+// FrameIndex returns the number of the frame at the current position, counting from 0.
+func (p *Player) FrameIndex() int {
+	return p.stream.frameIndex(p.Position())
+}
+
+func (p *Player) FrameCount() int {
+	return len(p.stream.Frames)
+}
+
+// SetAdaptive turns the adaptive frame rate on or off. Turned off, every frame of the stream is sent.
+func (p *Player) SetAdaptive(adaptive bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.adaptive = adaptive
+	p.rate = int(p.stream.FrameRate)
+	p.latency = 0
+	p.slot = -1
+}
+
+func (p *Player) Adaptive() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.adaptive
+}
+
+// SetAudio makes audio follow the player, starting it straight away if the player is playing. It's only set once.
+func (p *Player) SetAudio(audio Audio) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.audio = newAudioFollower(audio, p.Position)
+	if p.playing {
+		p.playAudio()
+	}
+}
+
+// AudioErr returns why the audio last failed to play or pause, or nil if it didn't.
+func (p *Player) AudioErr() error {
+	p.mutex.Lock()
+	audio := p.audio
+	p.mutex.Unlock()
+
+	if audio == nil {
+		return nil
+	}
+	return audio.Err()
+}
+
+// SyncAudio moves the audio back to the current position, such as after its offset has changed.
+func (p *Player) SyncAudio() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.playing {
+		p.playAudio()
+	}
+}
+
+// PlaybackOptions change how a stream is played: looped, repeating part of it, faster, slower or backwards.
+type PlaybackOptions struct {
+	Loop bool
+	// Plays from RepeatFrom to RepeatTo over and over, when RepeatTo is after RepeatFrom
+	RepeatFrom time.Duration
+	RepeatTo   time.Duration
+	// From 0.25 to 4 times normal speed. 0 is normal speed.
+	Speed   float64
+	Reverse bool
+}
+
+// SetOptions applies all of options at once.
+func (p *Player) SetOptions(options PlaybackOptions) {
+	p.SetLoop(options.Loop)
+	p.SetRepeat(options.RepeatFrom, options.RepeatTo)
+	p.SetReverse(options.Reverse)
+	if options.Speed != 0 {
+		p.SetSpeed(options.Speed)
+	}
+}
+
+// SetLoop makes playback start over at the end, instead of stopping.
+func (p *Player) SetLoop(loop bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.rebase()
+	p.loop = loop
+}
+
+func (p *Player) Loop() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.loop
+}
+
+// SetRepeat plays from one position to another over and over. Positions the wrong way round are swapped,
+// and setting both to the same position turns A-B repeat off.
+func (p *Player) SetRepeat(from time.Duration, to time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	from = min(max(from, 0), p.Duration())
+	to = min(max(to, 0), p.Duration())
+	if to < from {
+		from, to = to, from
+	}
+
+	// Playback moves into the repeated part straight away, like seeking
+	p.rebase()
+	p.repeatFrom, p.repeatTo = from, to
+	p.resend = true
+	if p.playing {
+		p.playAudio()
+	}
+	p.notify()
+}
+
+// Repeat returns the part of the stream that A-B repeat plays, if it's on.
+func (p *Player) Repeat() (from time.Duration, to time.Duration, repeating bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.repeatFrom, p.repeatTo, p.repeatTo > p.repeatFrom
+}
+
+const (
+	MinSpeed = 0.25
+	MaxSpeed = 4
+)
+
+// SetSpeed changes how fast the stream plays, rounded to a quarter and kept between MinSpeed and MaxSpeed.
+// Frames are still sent at the frame rate, so faster playback skips frames and slower playback holds them.
+func (p *Player) SetSpeed(speed float64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.rebase()
+	p.speed = int(math.Round(min(max(speed, MinSpeed), MaxSpeed) * 4))
+	// Slots are a different length in the stream at a different speed, so the next send can't be counted as dropping frames
+	p.slot = -1
+	if p.playing {
+		p.playAudio()
+	}
+	p.notify()
+}
+
+func (p *Player) Speed() float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return float64(p.speed) / 4
+}
+
+// SetReverse plays the stream backwards.
+func (p *Player) SetReverse(reverse bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.rebase()
+	p.reverse = reverse
+	p.slot = -1
+	if p.playing {
+		p.playAudio()
+	}
+	p.notify()
+}
+
+func (p *Player) Reverse() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.reverse
+}
+
+// Stats returns the counts for everything played so far, and the latency of the most recent frames.
+func (p *Player) Stats() PlaybackStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	stats := p.stats
+	stats.AverageLatency, stats.P95Latency = p.latencies.summary()
+	stats.FrameRate = p.rate
+	return stats
+}
+
+// TotalStats is Stats with the latency of every frame sent, for summing up a whole playback.
+func (p *Player) TotalStats() PlaybackStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	stats := p.stats
+	stats.AverageLatency, stats.P95Latency = p.totals.summary()
+	stats.FrameRate = p.rate
+	return stats
+}
+
+// Ended receives when playback reaches the end of the stream.
+func (p *Player) Ended() <-chan struct{} {
+	return p.ended
+}
+
+// Close stops the player, waiting for a frame that's being sent.
+func (p *Player) Close() {
+	p.close.Do(func() {
+		close(p.closed)
+	})
+	<-p.done
+
+	// The audio asks for the position while it's starting, so it's closed without holding the lock
+	p.mutex.Lock()
+	audio := p.audio
+	p.mutex.Unlock()
+
+	if audio != nil {
+		audio.Close()
+	}
+}
+
+func (p *Player) position() time.Duration {
+	if !p.playing {
+		return p.offset
+	}
+
+	elapsed := p.now().Sub(p.started) * time.Duration(p.speed) / 4
+	position := p.offset + elapsed
+	if p.reverse {
+		position = p.offset - elapsed
+	}
+
+	if from, to, looping := p.loopRange(); looping {
+		position = from + ((position-from)%(to-from)+(to-from))%(to-from)
+	}
+
+	return min(max(position, 0), p.Duration())
+}
+
+// rebase restarts the position's clock from where playback is now, so what changes next only applies from here on.
+func (p *Player) rebase() {
+	p.offset = p.position()
+	p.started = p.now()
+}
+
+// loopRange returns the part of the stream that's played over and over, if there is one.
+func (p *Player) loopRange() (from time.Duration, to time.Duration, looping bool) {
+	switch {
+	case p.repeatTo > p.repeatFrom:
+		return p.repeatFrom, p.repeatTo, true
+	case p.loop && p.Duration() > 0:
+		return 0, p.Duration(), true
+	}
+
+	return 0, 0, false
+}
+
+func (p *Player) playAudio() {
+	if p.audio == nil {
+		return
+	}
+
+	// External players can't change speed or play backwards, so the audio is only played at normal speed
+	if p.speed != 4 || p.reverse {
+		p.audio.pause()
+		return
+	}
+
+	p.audio.play()
+}
+
+func (p *Player) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Player) run() {
+	defer close(p.done)
+
+	for {
+		wait, playing := p.step()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if playing {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+
+		select {
+		case <-timeout:
+		case <-p.wake:
+		case <-p.closed:
+			return
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// step sends the frame at the current position if it hasn't been sent yet, and returns how long until the next frame is due.
+// playing is false when there's nothing to wait for.
+func (p *Player) step() (wait time.Duration, playing bool) {
+	if len(p.stream.Frames) == 0 {
+		return 0, false
+	}
+
+	p.mutex.Lock()
+	position := p.position()
+	_, _, looping := p.loopRange()
+	if p.playing && !looping && (!p.reverse && position >= p.Duration() || p.reverse && position <= 0) {
+		p.playing = false
+		p.offset = position
+		if p.audio != nil {
+			p.audio.pause()
+		}
+		select {
+		case p.ended <- struct{}{}:
+		default:
+		}
+	}
+
+	// Frames are sent on a grid of rate slots a second, laid over the stream in the direction it's played.
+	// Below the stream's frame rate, or above normal speed, that resamples it evenly.
+	slot := slotAt(p.distance(position), p.rate, p.speed)
+	index := p.slotFrame(slot)
+	send := index != p.index || p.resend
+	switch {
+	// Frames skipped by seeking weren't dropped
+	case p.slot >= 0 && slot > p.slot+1 && !p.resend:
+		p.stats.Dropped += slot - p.slot - 1
+	// Looping went back round, and the audio has to go back with it
+	case p.slot >= 0 && slot < p.slot && !p.resend && p.playing:
+		p.playAudio()
+	}
+	p.index = index
+	p.slot = slot
+	p.resend = false
+	p.mutex.Unlock()
+
+	// Sent without holding the lock, so the UI can keep seeking and pausing while a slow send is in flight
+	var err error
+	var latency time.Duration
+	if send {
+		started := p.now()
+		err = p.output.Send(&p.stream.Frames[index])
+		latency = p.now().Sub(started)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if send && err != nil {
+		p.stats.Failed++
+	} else if send {
+		p.stats.Sent++
+		p.latencies.add(latency)
+		p.totals.add(latency)
+	}
+
+	if send && p.adaptive {
+		p.adapt(latency)
+	}
+
+	if !p.playing {
+		return 0, false
+	}
+
+	// Due times come from the slot's place in the stream, so waiting never adds up any error
+	position = p.position()
+	distance := p.distance(position)
+	wait = slotTime(slotAt(distance, p.rate, p.speed)+1, p.rate, p.speed) - distance
+
+	// Looping goes back round before the next slot if it's nearer
+	if from, to, looping := p.loopRange(); looping {
+		untilWrap := to - position
+		if p.reverse {
+			untilWrap = position - from
+		}
+		wait = min(wait, untilWrap)
+	}
+
+	// Converted from the stream's time to real time, rounded up
+	return (wait*4 + time.Duration(p.speed) - 1) / time.Duration(p.speed), true
+}
+
+// distance returns how far position is from where playback started, going forwards or backwards.
+func (p *Player) distance(position time.Duration) time.Duration {
+	if p.reverse {
+		return p.Duration() - position
+	}
+
+	return position
+}
+
+// slotFrame returns the frame sent in slot, which is the one being played as the slot starts.
+func (p *Player) slotFrame(slot int) int {
+	start := slotTime(slot, p.rate, p.speed)
+	if p.reverse {
+		return p.stream.frameIndex(p.Duration() - start - 1)
+	}
+
+	return p.stream.frameIndex(start)
+}
+
+const adaptiveMinFrameRate = 2
+
+// How long the send rate has to stay the same before it's raised, so it doesn't bounce straight back after lowering
+const adaptiveRampInterval = time.Second
+
+// adapt lowers the send rate as soon as sends take most of a slot, and raises it a frame at a time once they're quick again.
+func (p *Player) adapt(latency time.Duration) {
+	// Smoothed, so a single slow send doesn't drop the rate
+	if p.latency == 0 {
+		p.latency = latency
+	} else {
+		p.latency = (p.latency*7 + latency) / 8
+	}
+
+	interval := time.Second / time.Duration(p.rate)
+	rate := p.rate
+
+	switch {
+	case p.latency > interval*9/10:
+		rate = max(min(rate*3/4, int(time.Second/p.latency)), adaptiveMinFrameRate)
+	case p.latency < interval/2 && p.now().Sub(p.lastRateChange) >= adaptiveRampInterval:
+		rate = min(rate+1, int(p.stream.FrameRate))
+	}
+
+	if rate != p.rate {
+		p.rate = rate
+		p.lastRateChange = p.now()
+		// Slot numbers change with the rate, so the next send can't be counted as dropping frames
+		p.slot = -1
+	}
+}
+
+// slotAt returns the slot at distance into the stream. Slots last 1/rate seconds of real time, which is speed/4 times that of the stream.
+func slotAt(distance time.Duration, rate int, speed int) int {
+	return int(int64(distance) * int64(rate) * 4 / (int64(speed) * int64(time.Second)))
+}
+
+// slotTime returns when slot starts, rounded up so it's never before slotAt would give slot.
+func slotTime(slot int, rate int, speed int) time.Duration {
+	slots := time.Duration(rate) * 4
+	return (time.Duration(slot)*time.Duration(speed)*time.Second + slots - 1) / slots
+}
@@ -0,0 +1,427 @@
+package internal
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	mutex sync.Mutex
+	time  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.time
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.time = c.time.Add(d)
+}
+
+// recordingTransport keeps the first pixel of every frame sent to it, and can be made slow.
+type recordingTransport struct {
+	mutex sync.Mutex
+	delay time.Duration
+	sent  []uint8
+}
+
+func (t *recordingTransport) Send(frame *Frame) error {
+	time.Sleep(t.delay)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.sent = append(t.sent, frame.Pixels[0][0])
+	return nil
+}
+
+func (t *recordingTransport) Close() error {
+	return nil
+}
+
+func (t *recordingTransport) String() string {
+	return "recording"
+}
+
+func (t *recordingTransport) Sent() []uint8 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return append([]uint8(nil), t.sent...)
+}
+
+func TestPlayerFollowsTheClock(t *testing.T) {
+	clock := &fakeClock{time: time.Now()}
+	transport := &recordingTransport{}
+	p := newPlayer(newTestStream(40, 10), transport, clock.now)
+
+	p.step()
+	if p.Position() != 0 || len(transport.Sent()) != 1 {
+		t.Fatal("expected a paused player to send the first frame and stay at 0")
+	}
+
+	p.Play()
+	clock.advance(time.Millisecond * 250)
+
+	wait, playing := p.step()
+	if !playing || p.Position() != time.Millisecond*250 {
+		t.Fatalf("expected the position to follow the clock to 250ms, got %s", p.Position())
+	}
+
+	// Frames are 100ms long, so frame 3 is due 50ms after 250ms
+	if wait != time.Millisecond*50 {
+		t.Errorf("expected to wait 50ms for the next frame, got %s", wait)
+	}
+
+	// Frame 1 was never due while a step ran, so it's dropped
+	if sent := transport.Sent(); len(sent) != 2 || sent[1] != 2 {
+		t.Errorf("expected frames 0 and 2 to be sent, got %v", sent)
+	}
+
+	p.Pause()
+	clock.advance(time.Second)
+	if p.Position() != time.Millisecond*250 {
+		t.Errorf("expected the position to hold while paused, got %s", p.Position())
+	}
+
+	p.Seek(time.Second * 3)
+	p.step()
+	if sent := transport.Sent(); sent[len(sent)-1] != 30 {
+		t.Errorf("expected seeking to send frame 30 straight away, got %d", sent[len(sent)-1])
+	}
+
+	p.Play()
+	clock.advance(time.Minute)
+	p.step()
+
+	if p.Playing() || p.Position() != p.Duration() {
+		t.Errorf("expected playback to stop at the end, got %s", p.Position())
+	}
+
+	select {
+	case <-p.Ended():
+	default:
+		t.Error("expected the end to be signalled")
+	}
+
+	p.Play()
+	if p.Position() != 0 {
+		t.Errorf("expected playing from the end to start over, got %s", p.Position())
+	}
+}
+
+func TestPlayerDropsFramesInsteadOfDrifting(t *testing.T) {
+	// Sends take 3 frames' worth of time
+	transport := &recordingTransport{delay: time.Millisecond * 30}
+	ps := newTestStream(50, 100)
+
+	p := NewPlayer(ps, transport)
+	defer p.Close()
+
+	started := time.Now()
+	p.Play()
+
+	select {
+	case <-p.Ended():
+	case <-time.After(time.Second * 5):
+		t.Fatal("expected playback to end")
+	}
+
+	// Tick accumulation would have taken 50 sends * 30ms = 1.5s
+	if elapsed := time.Since(started); elapsed > time.Millisecond*900 {
+		t.Errorf("expected playback to take about 500ms, took %s", elapsed)
+	}
+
+	sent := transport.Sent()
+	if len(sent) >= len(ps.Frames) {
+		t.Errorf("expected late frames to be dropped, but all %d were sent", len(sent))
+	}
+
+	for i := 1; i < len(sent); i++ {
+		if sent[i] <= sent[i-1] {
+			t.Errorf("expected frames in order, got %d after %d", sent[i], sent[i-1])
+		}
+	}
+}
+
+func TestLatencyRing(t *testing.T) {
+	var ring latencyRing
+	if average, p95 := ring.summary(); average != 0 || p95 != 0 {
+		t.Error("expected no latency without any frames")
+	}
+
+	for i := 20; i > 0; i-- {
+		ring.add(time.Duration(i) * time.Millisecond)
+	}
+
+	average, p95 := ring.summary()
+	if average != time.Microsecond*10500 {
+		t.Errorf("expected an average of 10.5ms, got %s", average)
+	}
+
+	if p95 != time.Millisecond*19 {
+		t.Errorf("expected a p95 of 19ms, got %s", p95)
+	}
+
+	// Only the most recent frames are kept
+	for i := 0; i < latencyWindow; i++ {
+		ring.add(time.Second)
+	}
+	if average, p95 := ring.summary(); average != time.Second || p95 != time.Second {
+		t.Errorf("expected the older latencies to be dropped, got an average of %s and a p95 of %s", average, p95)
+	}
+}
+
+func TestLatencyTotals(t *testing.T) {
+	var totals latencyTotals
+	if average, p95 := totals.summary(); average != 0 || p95 != 0 {
+		t.Error("expected no latency without any frames")
+	}
+
+	// Far more frames than the ring keeps, most of them fast
+	for i := 0; i < latencyWindow*10; i++ {
+		latency := time.Millisecond*3 + time.Microsecond*200
+		if i%100 < 10 {
+			latency = time.Millisecond * 40
+		}
+		totals.add(latency)
+	}
+	for i := 0; i < latencyWindow; i++ {
+		totals.add(time.Millisecond)
+	}
+
+	average, p95 := totals.summary()
+	if average < time.Millisecond*6 || average > time.Millisecond*7 {
+		t.Errorf("expected the average of every frame, got %s", average)
+	}
+	if p95 != time.Millisecond*40 {
+		t.Errorf("expected a p95 of 40ms across the whole run, got %s", p95)
+	}
+
+	// The estimate never goes past the slowest frame, or past the last bucket
+	totals = latencyTotals{}
+	totals.add(time.Microsecond * 300)
+	totals.add(time.Minute)
+	if _, p95 := totals.summary(); p95 != time.Minute {
+		t.Errorf("expected a p95 of the slowest frame, got %s", p95)
+	}
+	totals = latencyTotals{}
+	totals.add(time.Microsecond * 300)
+	if _, p95 := totals.summary(); p95 != time.Microsecond*300 {
+		t.Errorf("expected a p95 no slower than the slowest frame, got %s", p95)
+	}
+}
+
+func TestStreamCountsFrames(t *testing.T) {
+	clock := newTestClock(t)
+
+	ps := newTestStream(8, 64)
+	stats := ps.Stream(context.Background(), output(), PlaybackOptions{})
+
+	if stats.Sent+stats.Dropped != len(ps.Frames) || stats.Failed != 0 {
+		t.Errorf("expected every frame to be sent or dropped, got %s", stats)
+	}
+
+	if clock.ReceivedFrameCount() != stats.Sent || stats.AverageLatency <= 0 {
+		t.Errorf("expected the latency of the %d frames the clock received, got %s", clock.ReceivedFrameCount(), stats)
+	}
+
+	Host = "http://127.0.0.1:1"
+	stats = ps.Stream(context.Background(), output(), PlaybackOptions{})
+	if stats.Failed == 0 || stats.Sent != 0 {
+		t.Errorf("expected sends to an unreachable clock to fail, got %s", stats)
+	}
+}
+
+// slowTransport takes cost to send each frame, as measured by clock.
+type slowTransport struct {
+	recordingTransport
+	clock *fakeClock
+	cost  time.Duration
+}
+
+func (t *slowTransport) Send(frame *Frame) error {
+	t.clock.advance(t.cost)
+	return t.recordingTransport.Send(frame)
+}
+
+func TestPlayerAdaptsFrameRate(t *testing.T) {
+	clock := &fakeClock{time: time.Now()}
+	transport := &slowTransport{clock: clock}
+	p := newPlayer(newTestStream(16*60, 16), transport, clock.now)
+	p.SetAdaptive(true)
+	p.Play()
+
+	run := func(d time.Duration) {
+		t.Helper()
+		end := clock.now().Add(d)
+		for clock.now().Before(end) {
+			wait, playing := p.step()
+			if !playing {
+				t.Fatal("expected playback to continue")
+			}
+			clock.advance(max(wait, 0))
+		}
+	}
+
+	// A link that manages 5 frames a second
+	transport.cost = time.Millisecond * 200
+	run(time.Second * 5)
+
+	if rate := p.Stats().FrameRate; rate > 5 || rate < adaptiveMinFrameRate {
+		t.Errorf("expected the rate to drop to what the link manages, got %d fps", rate)
+	}
+
+	// Frames sent at a lower rate are still spread evenly over the stream, give or take rounding to whole frames
+	sent := transport.Sent()
+	last := sent[len(sent)-6:]
+	for i := 2; i < len(last); i++ {
+		if step, previous := int(last[i]-last[i-1]), int(last[i-1]-last[i-2]); step-previous > 1 || previous-step > 1 {
+			t.Errorf("expected evenly spaced frames, got %v", last)
+			break
+		}
+	}
+
+	transport.cost = time.Millisecond * 5
+	run(time.Second * 20)
+
+	if rate := p.Stats().FrameRate; rate != 16 {
+		t.Errorf("expected the rate to ramp back up to 16 fps, got %d", rate)
+	}
+
+	p.SetAdaptive(false)
+	if rate := p.Stats().FrameRate; rate != 16 {
+		t.Errorf("expected every frame to be sent once adaptive is off, got %d fps", rate)
+	}
+}
+
+func TestPlayerLoopsAndRepeats(t *testing.T) {
+	clock := &fakeClock{time: time.Now()}
+	transport := &recordingTransport{}
+	p := newPlayer(newTestStream(40, 10), transport, clock.now)
+
+	p.SetLoop(true)
+	p.Play()
+	clock.advance(time.Millisecond * 4500)
+	if _, playing := p.step(); !playing || p.Position() != time.Millisecond*500 {
+		t.Errorf("expected looping to go back round to 500ms, got %s", p.Position())
+	}
+
+	// Close to the end, the wait runs out where the loop goes back round rather than at the next frame
+	p.Seek(time.Millisecond * 3950)
+	if wait, _ := p.step(); wait != time.Millisecond*50 {
+		t.Errorf("expected to wait 50ms for the loop, got %s", wait)
+	}
+
+	p.SetLoop(false)
+	p.SetRepeat(time.Second*3, time.Second)
+	if from, to, repeating := p.Repeat(); !repeating || from != time.Second || to != time.Second*3 {
+		t.Errorf("expected markers the wrong way round to be swapped, got %s-%s", from, to)
+	}
+
+	p.Seek(time.Millisecond * 2900)
+	p.step()
+	clock.advance(time.Millisecond * 300)
+	p.step()
+	if p.Position() != time.Millisecond*1200 {
+		t.Errorf("expected A-B repeat to go back to A, got %s", p.Position())
+	}
+
+	if sent := transport.Sent(); sent[len(sent)-1] != 12 {
+		t.Errorf("expected frame 12 after going back round, got %d", sent[len(sent)-1])
+	}
+
+	p.SetRepeat(0, 0)
+	clock.advance(time.Minute)
+	p.step()
+	if p.Playing() {
+		t.Error("expected playback to end once A-B repeat is off")
+	}
+}
+
+func TestPlayerSpeedAndReverse(t *testing.T) {
+	clock := &fakeClock{time: time.Now()}
+	transport := &recordingTransport{}
+	p := newPlayer(newTestStream(40, 10), transport, clock.now)
+
+	p.SetSpeed(2)
+	p.Play()
+	clock.advance(time.Millisecond * 500)
+	wait, _ := p.step()
+	if p.Position() != time.Second {
+		t.Errorf("expected double speed to be at 1s after 500ms, got %s", p.Position())
+	}
+
+	// Frames are still sent at 10 a second, every other frame of the stream
+	if wait != time.Millisecond*100 {
+		t.Errorf("expected the next frame in 100ms, got %s", wait)
+	}
+
+	for i := 0; i < 3; i++ {
+		clock.advance(wait)
+		wait, _ = p.step()
+	}
+	if sent := transport.Sent(); !slices.Equal(sent[len(sent)-3:], []uint8{12, 14, 16}) {
+		t.Errorf("expected every other frame, got %v", sent)
+	}
+	if stats := p.Stats(); stats.Dropped != 0 {
+		t.Errorf("expected frames skipped for speed not to count as dropped, got %d", stats.Dropped)
+	}
+
+	p.SetSpeed(10)
+	if p.Speed() != MaxSpeed {
+		t.Errorf("expected the speed to be limited to %gx, got %gx", float64(MaxSpeed), p.Speed())
+	}
+
+	p.SetSpeed(0.25)
+	p.SetReverse(true)
+	position := p.Position()
+	clock.advance(time.Second * 2)
+	wait, _ = p.step()
+	if p.Position() != position-time.Millisecond*500 {
+		t.Errorf("expected to go back 500ms in 2s at a quarter speed, got from %s to %s", position, p.Position())
+	}
+
+	// The frame is held for 4 slots at a quarter speed
+	if wait != time.Millisecond*100 {
+		t.Errorf("expected to check again in 100ms, got %s", wait)
+	}
+
+	if sent := transport.Sent(); sent[len(sent)-1] != 10 {
+		t.Errorf("expected frame 10 playing backwards from 1.1s, got %d", sent[len(sent)-1])
+	}
+
+	p.SetSpeed(4)
+	clock.advance(time.Minute)
+	p.step()
+	if p.Playing() || p.Position() != 0 {
+		t.Errorf("expected playing backwards to end at the start, got %s", p.Position())
+	}
+
+	p.Play()
+	if p.Position() != p.Duration() {
+		t.Errorf("expected playing backwards again to start from the end, got %s", p.Position())
+	}
+}
+
+func TestStreamLoops(t *testing.T) {
+	transport := &recordingTransport{}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*300)
+	defer cancel()
+
+	stats := newTestStream(5, 50).Stream(ctx, transport, PlaybackOptions{Loop: true, Speed: 2})
+	// One pass takes 50ms at double speed, so it should have gone round a few times
+	if stats.Sent <= 5 {
+		t.Errorf("expected a looping stream to go round until stopped, got %v", transport.Sent())
+	}
+}
@@ -0,0 +1,271 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type RepeatMode int
+
+const (
+	RepeatOff RepeatMode = iota
+	RepeatAll
+	RepeatOne
+)
+
+func (r RepeatMode) String() string {
+	switch r {
+	case RepeatAll:
+		return "all"
+	case RepeatOne:
+		return "one"
+	default:
+		return "off"
+	}
+}
+
+// Playlists can be M3U files, or JSON files holding a list of paths. Relative paths are relative to the playlist.
+var playlistFileExts = []string{".m3u", ".m3u8", ".json"}
+
+// Playlist is a list of files that are played one after another.
+type Playlist struct {
+	Items  []FileLocation
+	Repeat RepeatMode
+
+	shuffle bool
+	// The play order as indexes into Items, and how far into it playback is
+	order    []int
+	position int
+}
+
+// Queue is the playlist that files are added to from Play Video.
+var Queue = &Playlist{}
+
+func IsPlaylistFile(fl FileLocation) bool {
+	for _, ext := range playlistFileExts {
+		if strings.HasSuffix(strings.ToLower(fl.Path), ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LoadPlaylist reads an M3U or JSON playlist.
+func LoadPlaylist(fl FileLocation) (*Playlist, error) {
+	data, err := fl.ReadFile()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	if strings.HasSuffix(strings.ToLower(fl.Path), ".json") {
+		err = json.Unmarshal(data, &paths)
+		if err != nil {
+			return nil, fmt.Errorf("expected a JSON list of paths: %w", err)
+		}
+	} else {
+		for _, line := range strings.Split(string(data), "\n") {
+			// Lines starting with # are comments or extended M3U information
+			line = strings.TrimSpace(strings.TrimPrefix(line, "\ufeff"))
+			if line != "" && !strings.HasPrefix(line, "#") {
+				paths = append(paths, line)
+			}
+		}
+	}
+
+	pl := &Playlist{}
+	for _, p := range paths {
+		if filepath.IsAbs(p) {
+			pl.Add(FromOSPath(p))
+		} else {
+			pl.Add(FileLocation{
+				System: fl.System,
+				Path:   path.Join(path.Dir(fl.Path), filepath.ToSlash(p)),
+			})
+		}
+	}
+
+	if pl.Len() == 0 {
+		return nil, fmt.Errorf("%s has nothing to play", path.Base(fl.Path))
+	}
+
+	return pl, nil
+}
+
+// Add appends fl to the end of the playlist, or of the play order when shuffled.
+func (pl *Playlist) Add(fl FileLocation) {
+	pl.Items = append(pl.Items, fl)
+	pl.order = append(pl.order, len(pl.Items)-1)
+}
+
+func (pl *Playlist) Len() int {
+	return len(pl.Items)
+}
+
+func (pl *Playlist) Clear() {
+	pl.Items = nil
+	pl.order = nil
+	pl.position = 0
+}
+
+// Current returns the file being played.
+func (pl *Playlist) Current() FileLocation {
+	return pl.Items[pl.order[pl.position]]
+}
+
+// Position returns how far into the play order playback is, counting from 0.
+func (pl *Playlist) Position() int {
+	return pl.position
+}
+
+// Next moves to the next file, starting over at the end if repeating. It returns false at the end of the playlist.
+func (pl *Playlist) Next() bool {
+	if pl.position < len(pl.order)-1 {
+		pl.position++
+		return true
+	}
+
+	if pl.Repeat == RepeatOff {
+		return false
+	}
+
+	// Every pass through a shuffled playlist is in a new order
+	if pl.shuffle {
+		rand.Shuffle(len(pl.order), func(i, j int) { pl.order[i], pl.order[j] = pl.order[j], pl.order[i] })
+	}
+	pl.position = 0
+	return true
+}
+
+// Previous moves back to the previous file, going round to the last one if repeating. On the first file it stays put.
+func (pl *Playlist) Previous() {
+	switch {
+	case pl.position > 0:
+		pl.position--
+	case pl.Repeat != RepeatOff:
+		pl.position = len(pl.order) - 1
+	}
+}
+
+// Jump makes the file at index of Items the current one.
+func (pl *Playlist) Jump(index int) {
+	for i, item := range pl.order {
+		if item == index {
+			pl.position = i
+		}
+	}
+}
+
+func (pl *Playlist) Shuffled() bool {
+	return pl.shuffle
+}
+
+// SetShuffle shuffles the play order, keeping the current file where playback is, or puts the playlist back in order.
+func (pl *Playlist) SetShuffle(shuffle bool) {
+	if shuffle == pl.shuffle || pl.Len() == 0 {
+		pl.shuffle = shuffle
+		return
+	}
+
+	pl.shuffle = shuffle
+	current := pl.order[pl.position]
+
+	if shuffle {
+		// The current file goes first, so it keeps playing
+		pl.order = rand.Perm(pl.Len())
+		for i, item := range pl.order {
+			if item == current {
+				pl.order[0], pl.order[i] = pl.order[i], pl.order[0]
+			}
+		}
+		pl.position = 0
+		return
+	}
+
+	for i := range pl.order {
+		pl.order[i] = i
+	}
+	pl.position = current
+}
+
+// CycleRepeat moves on to the next repeat mode: off, all, one.
+func (pl *Playlist) CycleRepeat() {
+	pl.Repeat = (pl.Repeat + 1) % 3
+}
+
+// Upcoming returns the files after the current one, in play order.
+func (pl *Playlist) Upcoming() []FileLocation {
+	var upcoming []FileLocation
+	for _, item := range pl.order[pl.position+1:] {
+		upcoming = append(upcoming, pl.Items[item])
+	}
+
+	return upcoming
+}
+
+// The background conversion of a playlist, if one is running
+var (
+	preconvertMutex  sync.Mutex
+	preconvertCancel context.CancelFunc
+	preconvertDone   chan struct{}
+)
+
+// Preconvert converts the files that haven't been converted yet, one at a time, so they're ready to play.
+// It returns straight away if files are already being converted, since the playlist is worked through again for every file played.
+func Preconvert(files []FileLocation, width int, height int) {
+	preconvertMutex.Lock()
+	if preconvertCancel != nil {
+		preconvertMutex.Unlock()
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	preconvertCancel, preconvertDone = cancel, done
+	preconvertMutex.Unlock()
+
+	defer func() {
+		preconvertMutex.Lock()
+		if preconvertDone == done {
+			preconvertCancel, preconvertDone = nil, nil
+		}
+		preconvertMutex.Unlock()
+
+		cancel()
+		close(done)
+	}()
+
+	for _, fl := range files {
+		if ctx.Err() != nil {
+			return
+		}
+		if fl.System != OS_FS || IsConverted(fl) {
+			continue
+		}
+
+		// A file that can't be converted is left for when it's played, which shows why
+		ConvertFile(ctx, fl, width, height, true)
+	}
+}
+
+// StopPreconverting cancels the background conversion of a playlist, and waits for ffmpeg to stop and its frames to be removed.
+func StopPreconverting() {
+	preconvertMutex.Lock()
+	cancel, done := preconvertCancel, preconvertDone
+	preconvertCancel, preconvertDone = nil, nil
+	preconvertMutex.Unlock()
+
+	if cancel == nil {
+		return
+	}
+
+	cancel()
+	<-done
+}
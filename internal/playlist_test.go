@@ -0,0 +1,302 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// writeTestPlaylist writes contents to a playlist file named name in dir and returns its location.
+func writeTestPlaylist(t *testing.T, dir string, name string, contents string) FileLocation {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	err := os.WriteFile(path, []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return FromOSPath(path)
+}
+
+func TestLoadPlaylist(t *testing.T) {
+	dir, err := filepath.Abs(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	absolute := filepath.Join(dir, "elsewhere", "c.pxlstrm")
+
+	expected := []string{
+		FromOSPath(filepath.Join(dir, "a.mp4")).Path,
+		FromOSPath(filepath.Join(dir, "clips", "b.mkv")).Path,
+		FromOSPath(absolute).Path,
+	}
+
+	for name, contents := range map[string]string{
+		"list.m3u":  "#EXTM3U\n#EXTINF:10,A\na.mp4\n\nclips/b.mkv\r\n" + absolute + "\n",
+		"list.json": `["a.mp4", "clips/b.mkv", "` + filepath.ToSlash(absolute) + `"]`,
+	} {
+		pl, err := LoadPlaylist(writeTestPlaylist(t, dir, name, contents))
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		var paths []string
+		for _, item := range pl.Items {
+			paths = append(paths, item.Path)
+		}
+
+		if !slices.Equal(paths, expected) {
+			t.Errorf("%s: expected %v, got %v", name, expected, paths)
+		}
+	}
+
+	for name, contents := range map[string]string{
+		"empty.m3u":   "#EXTM3U\n",
+		"object.json": `{"items": []}`,
+	} {
+		if _, err := LoadPlaylist(writeTestPlaylist(t, dir, name, contents)); err == nil {
+			t.Errorf("expected %s to be rejected", name)
+		}
+	}
+}
+
+// newTestPlaylist returns a playlist of count made up files.
+func newTestPlaylist(count int) *Playlist {
+	pl := &Playlist{}
+	for i := 0; i < count; i++ {
+		pl.Add(FileLocation{System: OS_FS, Path: string(rune('a' + i))})
+	}
+
+	return pl
+}
+
+func TestPlaylistOrder(t *testing.T) {
+	pl := newTestPlaylist(3)
+
+	pl.Previous()
+	if pl.Current().Path != "a" {
+		t.Errorf("expected previous on the first file to stay there, got %s", pl.Current().Path)
+	}
+
+	if !pl.Next() || !pl.Next() || pl.Current().Path != "c" {
+		t.Fatalf("expected to move through to c, got %s", pl.Current().Path)
+	}
+
+	if pl.Next() {
+		t.Error("expected the end of the playlist without repeat")
+	}
+
+	pl.CycleRepeat()
+	if pl.Repeat != RepeatAll || !pl.Next() || pl.Current().Path != "a" {
+		t.Errorf("expected repeat all to start over, got %s", pl.Current().Path)
+	}
+
+	pl.Previous()
+	if pl.Current().Path != "c" {
+		t.Errorf("expected previous to go round to the last file, got %s", pl.Current().Path)
+	}
+
+	pl.CycleRepeat()
+	pl.CycleRepeat()
+	if pl.Repeat != RepeatOff {
+		t.Errorf("expected repeat to cycle back to off, got %s", pl.Repeat)
+	}
+}
+
+func TestPlaylistShuffle(t *testing.T) {
+	pl := newTestPlaylist(20)
+	pl.Jump(7)
+
+	pl.SetShuffle(true)
+	if pl.Current().Path != pl.Items[7].Path || pl.Position() != 0 {
+		t.Fatalf("expected shuffling to keep playing the current file, got %s", pl.Current().Path)
+	}
+
+	seen := map[string]bool{pl.Current().Path: true}
+	for _, item := range pl.Upcoming() {
+		seen[item.Path] = true
+	}
+	if len(seen) != 20 {
+		t.Errorf("expected every file to be played once, got %d", len(seen))
+	}
+
+	pl.Next()
+	current := pl.Current().Path
+	pl.SetShuffle(false)
+	if pl.Current().Path != current || pl.Items[pl.Position()].Path != current {
+		t.Errorf("expected turning shuffle off to carry on from %s in order, got %s", current, pl.Current().Path)
+	}
+}
+
+func TestPlayModePlaylist(t *testing.T) {
+	newTestClock(t)
+
+	first := newTestStream(8, 16)
+	second := newTestStream(8, 16)
+	for i := range second.Frames {
+		second.Frames[i].Pixels[0][1] = 1
+	}
+
+	pl := &Playlist{}
+	pl.Add(saveTestStream(t, first))
+	pl.Add(saveTestStream(t, second))
+
+	// start runs the loading messages of the current file, and the messages that start its player
+	start := func(m tea.Model, loaded []tea.Msg) PlayMode {
+		t.Helper()
+
+		m, cmds := update(m, loaded...)
+		for _, cmd := range cmds {
+			m, _ = update(m, runCmd(cmd)...)
+		}
+
+		pm := m.(PlayMode)
+		if pm.player == nil {
+			t.Fatalf("expected %s to play, got %s", pm.file.Path, pm.stateMessage)
+		}
+		t.Cleanup(pm.player.Close)
+
+		return pm
+	}
+
+	m := NewPlaylistMode(pl)
+	firstLoaded := runCmd(m.Init())
+	m = start(m, firstLoaded)
+
+	// Skipping loads the next file
+	model, cmd := m.Update(keyPress("n"))
+	if model.(PlayMode).file.Path != pl.Items[1].Path {
+		t.Fatalf("expected n to move on to the second file, got %s", model.(PlayMode).file.Path)
+	}
+	m = start(model, runCmd(cmd))
+
+	if m.frame.Pixels[0][1] != 1 {
+		t.Error("expected the second file to be playing")
+	}
+
+	// Messages still on their way for the first file don't take over
+	model, _ = update(m, firstLoaded...)
+	if model.(PlayMode).pixelstream != m.pixelstream {
+		t.Error("expected messages for the first file to be ignored")
+	}
+
+	// At the end of the last file, repeat all goes back to the first
+	pl.Repeat = RepeatAll
+	for deadline := time.Now().Add(time.Second * 5); m.player.Playing(); time.Sleep(time.Millisecond * 10) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected playback to end")
+		}
+	}
+
+	model, _ = m.Update(playModeTickMsg{player: m.player})
+	if model.(PlayMode).file.Path != pl.Items[0].Path {
+		t.Errorf("expected the playlist to go round to the first file, got %s", model.(PlayMode).file.Path)
+	}
+
+	if view := model.View(); !strings.Contains(view, "1/2") || !strings.Contains(view, "Repeat all") {
+		t.Errorf("expected the view to show the playlist, got:\n%s", view)
+	}
+}
+
+func TestPlayModePlaylistKeepsTickingAtTheEnd(t *testing.T) {
+	newTestClock(t)
+
+	pl := &Playlist{}
+	pl.Add(saveTestStream(t, newTestStream(8, 16)))
+
+	m := NewPlaylistMode(pl)
+	model, cmds := update(m, runCmd(m.Init())...)
+	for _, cmd := range cmds {
+		model, _ = update(model, runCmd(cmd)...)
+	}
+	m = model.(PlayMode)
+	t.Cleanup(m.player.Close)
+
+	// The end is left for the tick to notice
+	waitForStop := func() {
+		t.Helper()
+		for deadline := time.Now().Add(time.Second * 5); m.player.Playing(); time.Sleep(time.Millisecond * 10) {
+			if time.Now().After(deadline) {
+				t.Fatal("expected playback to end")
+			}
+		}
+	}
+
+	waitForStop()
+	model, cmd := m.Update(playModeTickMsg{player: m.player})
+	if model.(PlayMode).player != m.player || cmd == nil {
+		t.Fatal("expected the view to keep refreshing once the playlist has ended")
+	}
+
+	// Playing again from the end is noticed when it ends again
+	model, _ = model.Update(keyPress(" "))
+	if !m.player.Playing() {
+		t.Fatal("expected space to play the last file again")
+	}
+
+	pl.Repeat = RepeatAll
+	waitForStop()
+	model, _ = model.Update(playModeTickMsg{player: m.player})
+	if model.(PlayMode).player == m.player {
+		t.Error("expected the playlist to start over once the file ends again")
+	}
+}
+
+func TestStopPreconverting(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffmpeg is a shell script")
+	}
+
+	// ffmpeg is stood in for by a script that never finishes
+	bin := t.TempDir()
+	err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte("#!/bin/sh\nexec sleep 10\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	video := filepath.Join(t.TempDir(), "video.mp4")
+	os.WriteFile(video, nil, 0644)
+
+	done := make(chan struct{})
+	go func() {
+		Preconvert([]FileLocation{FromOSPath(video)}, DefaultFrameWidth, DefaultFrameHeight)
+		close(done)
+	}()
+
+	// Wait for ffmpeg's frame directory to be made
+	for deadline := time.Now().Add(time.Second * 2); time.Now().Before(deadline); time.Sleep(time.Millisecond * 10) {
+		if entries, _ := os.ReadDir(tmp); len(entries) > 0 {
+			break
+		}
+	}
+
+	start := time.Now()
+	StopPreconverting()
+	if time.Since(start) > time.Second {
+		t.Errorf("expected ffmpeg to be stopped straight away, took %s", time.Since(start))
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the conversion to end once stopped")
+	}
+
+	if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+		t.Errorf("expected the frame directory to be removed, found %v", entries)
+	}
+	if IsConverted(FromOSPath(video)) {
+		t.Error("expected nothing to be saved for a cancelled conversion")
+	}
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// RecentMode lists the files played most recently, along with where they were left.
+type RecentMode struct {
+	entries  []HistoryEntry
+	selected int
+	loaded   bool
+	err      error
+}
+
+func NewRecentMode() RecentMode {
+	return RecentMode{}
+}
+
+type recentMsg struct {
+	entries []HistoryEntry
+	err     error
+}
+
+// The history is read every time the mode is opened, since it changes with everything played
+func (m RecentMode) Init() tea.Cmd {
+	return func() tea.Msg {
+		entries, err := LoadHistory()
+		return recentMsg{entries: entries, err: err}
+	}
+}
+
+func (m RecentMode) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+
+		case "q":
+			return NewMenuMode(), nil
+
+		case "up", "k":
+			if m.selected > 0 {
+				m.selected--
+			}
+
+		case "down", "j":
+			if m.selected < len(m.entries)-1 {
+				m.selected++
+			}
+
+		case "enter":
+			if m.selected >= len(m.entries) {
+				return m, nil
+			}
+
+			return SwitchMode(NewPlayMode(FromOSPath(m.entries[m.selected].Path)))
+		}
+
+	case recentMsg:
+		m.loaded = true
+		m.entries = msg.entries
+		m.err = msg.err
+		m.selected = min(m.selected, max(len(m.entries)-1, 0))
+	}
+
+	return m, nil
+}
+
+func (m RecentMode) View() string {
+	var s strings.Builder
+
+	s.WriteString("Recently played:\n\n")
+
+	switch {
+	case m.err != nil:
+		s.WriteString("Couldn't read the history: ")
+		s.WriteString(m.err.Error())
+		s.WriteRune('\n')
+	case !m.loaded:
+	case HistoryFile == "":
+		s.WriteString("History is turned off.\n")
+	case len(m.entries) == 0:
+		s.WriteString("Nothing has been played yet.\n")
+	default:
+		for i, entry := range m.entries {
+			label := fmt.Sprintf("%s  %s / %s", filepath.Base(entry.Path), FmtDuration(entry.Position), FmtDuration(entry.Duration))
+
+			if i == m.selected {
+				s.WriteString(selectedItemStyle.Render("> " + label))
+			} else {
+				s.WriteString(itemStyle.Render(label))
+			}
+			s.WriteRune('\n')
+		}
+	}
+
+	s.WriteRune('\n')
+	s.WriteString(helpStyle("[q] back  [↑/↓] select  [enter] play\n"))
+
+	return s.String()
+}
@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"time"
+)
+
+// Recorder captures frames as they arrive so they can be turned into a fixed frame rate PixelStream.
+type Recorder struct {
+	start  time.Time
+	times  []time.Duration
+	frames []Frame
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		start: time.Now(),
+	}
+}
+
+func (r *Recorder) Add(t time.Time, frame Frame) {
+	r.times = append(r.times, t.Sub(r.start))
+	r.frames = append(r.frames, frame)
+}
+
+func (r *Recorder) Elapsed() time.Duration {
+	return time.Since(r.start)
+}
+
+func (r *Recorder) FrameCount() int {
+	return len(r.frames)
+}
+
+// PixelStream resamples the captured frames to frameRate, holding each captured frame until the next one arrived.
+func (r *Recorder) PixelStream(frameRate uint8) *PixelStream {
+	ps := &PixelStream{
+		Version:   pixelstreamFormatVersion,
+		FrameRate: frameRate,
+		Width:     DefaultFrameWidth,
+		Height:    DefaultFrameHeight,
+	}
+
+	if len(r.frames) == 0 {
+		return ps
+	}
+
+	// The screen size can't change mid recording on a real clock, but anything else is scaled to the first frame
+	ps.Width = r.frames[0].Width
+	ps.Height = r.frames[0].Height
+
+	duration := r.times[len(r.times)-1]
+	frameCount := int(duration.Seconds()*float64(frameRate)) + 1
+	ps.Frames = make([]Frame, frameCount)
+
+	source := 0
+	for i := range ps.Frames {
+		t := time.Duration(i) * time.Second / time.Duration(frameRate)
+		for source+1 < len(r.times) && r.times[source+1] <= t {
+			source++
+		}
+		ps.Frames[i] = *r.frames[source].Resize(ps.Width, ps.Height)
+	}
+
+	return ps
+}
@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecorderResamples(t *testing.T) {
+	frames := newTestStream(3, 16).Frames
+	// The last frame is one colour, so it's still told apart once rescaled
+	for i := range frames[2].Pixels {
+		frames[2].Pixels[i] = [3]uint8{2, 0, 255}
+	}
+
+	r := NewRecorder()
+	if ps := r.PixelStream(4); len(ps.Frames) != 0 {
+		t.Errorf("expected an empty stream before anything is captured, got %d frames", len(ps.Frames))
+	}
+
+	// Frames arrive unevenly, and the last one at a different size
+	r.Add(r.start, frames[0])
+	r.Add(r.start.Add(time.Millisecond*300), frames[1])
+	r.Add(r.start.Add(time.Second), *frames[2].Resize(16, 8))
+
+	ps := r.PixelStream(4)
+	if ps.FrameRate != 4 || ps.Width != DefaultFrameWidth || ps.Height != DefaultFrameHeight {
+		t.Fatalf("expected a %dx%d stream at 4 fps, got %dx%d at %d fps", DefaultFrameWidth, DefaultFrameHeight, ps.Width, ps.Height, ps.FrameRate)
+	}
+
+	// Each captured frame is held until the next one arrived
+	expected := []uint8{0, 0, 1, 1, 2}
+	if len(ps.Frames) != len(expected) {
+		t.Fatalf("expected %d frames over 1s at 4 fps, got %d", len(expected), len(ps.Frames))
+	}
+	for i, first := range expected {
+		if ps.Frames[i].Width != ps.Width || ps.Frames[i].Pixels[0][0] != first {
+			t.Errorf("expected frame %d to be captured frame %d at the stream's size, got %d at %dx%d", i, first, ps.Frames[i].Pixels[0][0], ps.Frames[i].Width, ps.Frames[i].Height)
+		}
+	}
+}
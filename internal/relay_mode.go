@@ -0,0 +1,177 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Notifications expire on the clock, so unchanged frames are still resent this often
+const relayKeepAlive = time.Second * 2
+
+type RelayMode struct {
+	source        string
+	sourceSize    DeviceInfo
+	targets       []Transport
+	targetSizes   []DeviceInfo
+	interval      time.Duration
+	skipUnchanged bool
+	frame         *Frame
+	lastSent      time.Time
+	relayed       int
+	skipped       int
+	sourceErr     error
+	targetErrs    []error
+}
+
+// NewRelayMode relays source to targets. sizes holds the matrix size of the source followed by each target,
+// and frames are rescaled for targets that differ from the source.
+func NewRelayMode(source string, targets []Transport, sizes []DeviceInfo, interval time.Duration, skipUnchanged bool) RelayMode {
+	return RelayMode{
+		source:        source,
+		sourceSize:    sizes[0],
+		targets:       targets,
+		targetSizes:   sizes[1:],
+		interval:      interval,
+		skipUnchanged: skipUnchanged,
+		targetErrs:    make([]error, len(targets)),
+	}
+}
+
+func (m RelayMode) Init() tea.Cmd {
+	return m.relayFrame(nil)
+}
+
+func (m RelayMode) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q":
+			return m, tea.Quit
+
+		case "u":
+			m.skipUnchanged = !m.skipUnchanged
+			return m, nil
+		}
+
+	case relayMsg:
+		m.sourceErr = msg.sourceErr
+		if msg.sourceErr == nil {
+			m.frame = msg.frame
+			if msg.sent {
+				m.relayed++
+				m.lastSent = msg.started
+				m.targetErrs = msg.targetErrs
+			} else {
+				m.skipped++
+			}
+		}
+
+		delay := max(m.interval-time.Since(msg.started), 0)
+		return m, tea.Tick(delay, func(_ time.Time) tea.Msg {
+			return relayTickMsg{}
+		})
+
+	case relayTickMsg:
+		var previous *Frame
+		if m.skipUnchanged && time.Since(m.lastSent) < relayKeepAlive {
+			previous = m.frame
+		}
+		return m, m.relayFrame(previous)
+	}
+
+	return m, nil
+}
+
+func (m RelayMode) View() string {
+	var s strings.Builder
+
+	s.WriteString("\nRelaying ")
+	s.WriteString(RedactHost(m.source))
+	s.WriteString(" → ")
+	for i, target := range m.targets {
+		if i > 0 {
+			s.WriteString(", ")
+		}
+		s.WriteString(target.String())
+	}
+	s.WriteString("\n\n")
+
+	if m.frame != nil {
+		s.WriteString(m.frame.View())
+	} else {
+		s.WriteString("Waiting for the first frame...\n")
+	}
+	s.WriteRune('\n')
+
+	fmt.Fprintf(&s, "Poll interval: %s  Skip unchanged: %t\n", m.interval, m.skipUnchanged)
+	fmt.Fprintf(&s, "Frames relayed: %d  Frames skipped: %d\n", m.relayed, m.skipped)
+
+	if m.sourceErr != nil {
+		s.WriteString("Error reading source: ")
+		s.WriteString(m.sourceErr.Error())
+		s.WriteRune('\n')
+	}
+
+	for i, err := range m.targetErrs {
+		if err != nil {
+			s.WriteString("Error sending to ")
+			s.WriteString(m.targets[i].String())
+			s.WriteString(": ")
+			s.WriteString(err.Error())
+			s.WriteRune('\n')
+		}
+	}
+
+	s.WriteRune('\n')
+	s.WriteString(helpStyle("[q] quit  [u] toggle skip unchanged\n"))
+
+	return s.String()
+}
+
+type relayTickMsg struct{}
+
+type relayMsg struct {
+	started    time.Time
+	frame      *Frame
+	sent       bool
+	sourceErr  error
+	targetErrs []error
+}
+
+// relayFrame reads the source screen and sends it to every target, unless it matches previous.
+func (m RelayMode) relayFrame(previous *Frame) tea.Cmd {
+	return func() tea.Msg {
+		msg := relayMsg{
+			started: time.Now(),
+		}
+
+		msg.frame, msg.sourceErr = ReadScreen(m.source, m.sourceSize, Mapping)
+		if msg.sourceErr != nil {
+			return msg
+		}
+
+		if previous != nil && previous.Equal(msg.frame) {
+			return msg
+		}
+
+		msg.sent = true
+		msg.targetErrs = make([]error, len(m.targets))
+
+		var wg sync.WaitGroup
+		for i, target := range m.targets {
+			wg.Add(1)
+			go func(i int, target Transport) {
+				defer wg.Done()
+				size := m.targetSizes[i]
+				msg.targetErrs[i] = target.Send(msg.frame.Resize(size.Width, size.Height))
+			}(i, target)
+		}
+		wg.Wait()
+
+		return msg
+	}
+}
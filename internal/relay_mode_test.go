@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRelayModeForwardsFrames(t *testing.T) {
+	newTestClock(t)
+
+	// The target has a smaller matrix, so frames are rescaled on the way
+	target := NewSimulatorWithSize(16, 8)
+	server := httptest.NewServer(target)
+	t.Cleanup(server.Close)
+
+	frame := newTestStream(4, 16).Frames[3]
+	err := frame.SendFrame(Host + "/api/notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sizes := []DeviceInfo{Device, {Width: 16, Height: 8}}
+	m := NewRelayMode(Host, []Transport{&AwtrixTransport{Host: server.URL}}, sizes, time.Millisecond, true)
+
+	model, cmd := m.Update(runCmd(m.Init())[0])
+	m = model.(RelayMode)
+	if cmd == nil {
+		t.Fatal("expected the source to be polled again")
+	}
+
+	received := target.ReceivedFrames()
+	if len(received) != 1 || !received[0].Equal(frame.Resize(16, 8)) {
+		t.Fatalf("expected the source's screen to be sent rescaled to the target, got %d frames", len(received))
+	}
+
+	// The same screen again isn't resent while skipping unchanged frames
+	model, _ = m.Update(runCmd(m.relayFrame(m.frame))[0])
+	m = model.(RelayMode)
+	if target.ReceivedFrameCount() != 1 || !strings.Contains(m.View(), "Frames relayed: 1  Frames skipped: 1") {
+		t.Errorf("expected an unchanged frame to be skipped, got:\n%s", m.View())
+	}
+
+	// A target that can't be reached is reported, without stopping the relay
+	server.Close()
+	model, cmd = m.Update(runCmd(m.relayFrame(nil))[0])
+	if cmd == nil || !strings.Contains(model.View(), "Error sending to") {
+		t.Errorf("expected the failed target to be shown, got:\n%s", model.View())
+	}
+}
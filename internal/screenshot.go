@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+)
+
+const DefaultScreenshotScale = 16
+
+// Image renders the frame with every pixel scaled up to a scale x scale block.
+// With dots set, each pixel is drawn as a round LED on a black background instead.
+func (f *Frame) Image(scale int, dots bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, f.Width*scale, f.Height*scale))
+
+	// Dot distances are measured in doubled units so block centers stay on integers, a dot spans 80% of its block
+	radius := scale * 4 / 5
+
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			pixel := f.Pixels[(y/scale)*f.Width+(x/scale)]
+			c := color.RGBA{pixel[0], pixel[1], pixel[2], 255}
+
+			if dots {
+				dx := 2*(x%scale) + 1 - scale
+				dy := 2*(y%scale) + 1 - scale
+				if dx*dx+dy*dy > radius*radius {
+					c = color.RGBA{0, 0, 0, 255}
+				}
+			}
+
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	return img
+}
+
+func (f *Frame) SavePNG(path string, scale int, dots bool) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = png.Encode(file, f.Image(scale, dots))
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
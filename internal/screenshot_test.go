@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScreenshot(t *testing.T) {
+	newTestClock(t)
+
+	frame := newTestStream(4, 16).Frames[3]
+	err := frame.SendFrame(Host + "/api/notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	screen, err := ReadScreen(Host, Device, Mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dots := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "screenshot.png")
+		err = screen.SavePNG(path, 4, dots)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(file)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if bounds := img.Bounds(); bounds.Dx() != Device.Width*4 || bounds.Dy() != Device.Height*4 {
+			t.Fatalf("expected a %dx%d image, got %dx%d", Device.Width*4, Device.Height*4, bounds.Dx(), bounds.Dy())
+		}
+
+		pixel := color.RGBAModel.Convert(img.At(1, 1)).(color.RGBA)
+		if pixel != (color.RGBA{3, 0, 255, 255}) {
+			t.Errorf("expected the first pixel to fill its block, got %v", pixel)
+		}
+
+		// Drawn as LEDs, the corners of each block are left black
+		corner := color.RGBAModel.Convert(img.At(0, 0)).(color.RGBA)
+		if dots && corner != (color.RGBA{0, 0, 0, 255}) {
+			t.Errorf("expected the corner of an LED to be black, got %v", corner)
+		}
+		if !dots && corner != pixel {
+			t.Errorf("expected square pixels to reach the corner, got %v", corner)
+		}
+	}
+}
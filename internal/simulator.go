@@ -0,0 +1,429 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const simulatorRequestLogLength = 10
+
+var simulatorBuiltinApps = []string{"Time", "Date", "Temperature", "Humidity", "Battery"}
+
+// Simulator emulates the parts of the awtrix HTTP API that pixelstream uses, so it can be developed and tested without a clock.
+type Simulator struct {
+	mutex        sync.Mutex
+	width        int
+	height       int
+	started      time.Time
+	settings     map[string]any
+	apps         []string
+	customFrames map[string]*Frame
+	currentApp   int
+	notification *Frame
+	notifyUntil  time.Time
+	recorder     *Recorder
+	requests     []string
+}
+
+func NewSimulator() *Simulator {
+	return NewSimulatorWithSize(DefaultFrameWidth, DefaultFrameHeight)
+}
+
+// NewSimulatorWithSize simulates a clock built with a width x height matrix.
+func NewSimulatorWithSize(width int, height int) *Simulator {
+	return &Simulator{
+		width:   width,
+		height:  height,
+		started: time.Now(),
+		settings: map[string]any{
+			"MATP":     true,
+			"BRI":      float64(100),
+			"ABRI":     false,
+			"ATRANS":   true,
+			"TEFF":     float64(1),
+			"TSPEED":   float64(500),
+			"ATIME":    float64(7),
+			"CCORRECT": []any{float64(255), float64(255), float64(255)},
+			"CTEMP":    []any{float64(255), float64(255), float64(255)},
+		},
+		apps:         append([]string(nil), simulatorBuiltinApps...),
+		customFrames: make(map[string]*Frame),
+		recorder:     NewRecorder(),
+	}
+}
+
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests = append(s.requests, time.Now().Format("15:04:05")+" "+r.Method+" "+r.URL.Path)
+	if len(s.requests) > simulatorRequestLogLength {
+		s.requests = s.requests[len(s.requests)-simulatorRequestLogLength:]
+	}
+
+	var err error
+
+	switch r.Method + " " + r.URL.Path {
+	case "GET /api/screen":
+		frame := s.screen()
+		colors := make([]uint32, len(frame.Pixels))
+		for i, pixel := range frame.Pixels {
+			colors[i] = (uint32(pixel[0]) << 16) | (uint32(pixel[1]) << 8) | uint32(pixel[2])
+		}
+		// Written without the trailing newline json.Encoder adds, to match the real clock
+		var data []byte
+		data, err = json.Marshal(colors)
+		if err == nil {
+			_, err = w.Write(data)
+		}
+
+	case "POST /api/notify":
+		var frame *Frame
+		frame, err = decodeDrawFrame(r, s.width, s.height)
+		if err == nil {
+			s.notification = frame
+			s.notifyUntil = time.Now().Add(time.Duration(s.number("ATIME", 7) * float64(time.Second)))
+			s.recorder.Add(time.Now(), *frame)
+		}
+
+	case "POST /api/notify/dismiss":
+		s.notification = nil
+
+	case "POST /api/custom":
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			err = errors.New("missing app name")
+			break
+		}
+
+		if r.ContentLength == 0 {
+			s.removeApp(name)
+			break
+		}
+
+		var frame *Frame
+		frame, err = decodeDrawFrame(r, s.width, s.height)
+		if err == nil {
+			if _, ok := s.customFrames[name]; !ok {
+				s.apps = append(s.apps, name)
+			}
+			s.customFrames[name] = frame
+			s.recorder.Add(time.Now(), *frame)
+		}
+
+	case "POST /api/nextapp":
+		s.currentApp = (s.currentApp + 1) % len(s.apps)
+
+	case "POST /api/previousapp":
+		s.currentApp = (s.currentApp - 1 + len(s.apps)) % len(s.apps)
+
+	case "POST /api/switch":
+		var body struct {
+			Name string `json:"name"`
+		}
+		err = json.NewDecoder(r.Body).Decode(&body)
+		if err == nil {
+			err = s.switchApp(body.Name)
+		}
+
+	case "GET /api/loop":
+		loop := make(map[string]int, len(s.apps))
+		for i, app := range s.apps {
+			loop[app] = i
+		}
+		err = json.NewEncoder(w).Encode(loop)
+
+	case "GET /api/settings":
+		err = json.NewEncoder(w).Encode(s.settings)
+
+	case "POST /api/settings":
+		var update map[string]any
+		err = json.NewDecoder(r.Body).Decode(&update)
+		for k, v := range update {
+			s.settings[k] = v
+		}
+
+	case "GET /api/stats":
+		err = json.NewEncoder(w).Encode(DeviceStats{
+			Battery:     100,
+			Lux:         42,
+			Brightness:  int(s.number("BRI", 0)),
+			Temperature: 21.5,
+			Humidity:    40,
+			FreeHeap:    150000,
+			Uptime:      int(time.Since(s.started).Seconds()),
+			WifiSignal:  -50,
+			Version:     "simulator",
+			UID:         "awtrix_simulator",
+			App:         s.apps[s.currentApp],
+		})
+
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// Screen returns what the simulated clock is currently displaying.
+func (s *Simulator) Screen() Frame {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.screen()
+}
+
+func (s *Simulator) screen() Frame {
+	frame := NewFrame(s.width, s.height)
+
+	if power, ok := s.settings["MATP"].(bool); ok && !power {
+		return *frame
+	}
+
+	if s.notification != nil && time.Now().Before(s.notifyUntil) {
+		return *s.notification
+	}
+
+	app := s.apps[s.currentApp]
+	if custom, ok := s.customFrames[app]; ok {
+		return *custom
+	}
+
+	// Built in apps are shown as a dim block of a color derived from their name
+	hash := fnv.New32a()
+	hash.Write([]byte(app))
+	color := hash.Sum32()
+	for i := range frame.Pixels {
+		frame.Pixels[i] = [3]uint8{uint8(color>>16) / 4, uint8(color>>8) / 4, uint8(color) / 4}
+	}
+
+	return *frame
+}
+
+// App returns the name of the app the simulated clock is on.
+func (s *Simulator) App() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.apps[s.currentApp]
+}
+
+// Settings returns a copy of the simulated clock's settings.
+func (s *Simulator) Settings() map[string]any {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	settings := make(map[string]any, len(s.settings))
+	for k, v := range s.settings {
+		settings[k] = v
+	}
+
+	return settings
+}
+
+// NotificationActive reports whether a notification is covering the current app.
+func (s *Simulator) NotificationActive() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.notification != nil && time.Now().Before(s.notifyUntil)
+}
+
+// ReceivedFrames returns every frame drawn through /api/notify or /api/custom, in the order they arrived.
+func (s *Simulator) ReceivedFrames() []Frame {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]Frame(nil), s.recorder.frames...)
+}
+
+func (s *Simulator) ReceivedFrameCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.recorder.FrameCount()
+}
+
+// Recording returns the received frames resampled to frameRate.
+func (s *Simulator) Recording(frameRate uint8) *PixelStream {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.recorder.PixelStream(frameRate)
+}
+
+// Requests returns the most recent requests, oldest first.
+func (s *Simulator) Requests() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+// number reads a numeric setting, falling back if a client stored something else in it.
+func (s *Simulator) number(key string, fallback float64) float64 {
+	if v, ok := s.settings[key].(float64); ok {
+		return v
+	}
+
+	return fallback
+}
+
+func (s *Simulator) switchApp(name string) error {
+	for i, app := range s.apps {
+		if app == name {
+			s.currentApp = i
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown app: %s", name)
+}
+
+// removeApp removes a custom app. Built in apps stay, so the loop is never empty.
+func (s *Simulator) removeApp(name string) {
+	if _, ok := s.customFrames[name]; !ok {
+		return
+	}
+
+	delete(s.customFrames, name)
+
+	for i, app := range s.apps {
+		if app == name {
+			s.apps = append(s.apps[:i], s.apps[i+1:]...)
+			if s.currentApp >= len(s.apps) {
+				s.currentApp = 0
+			}
+			return
+		}
+	}
+}
+
+// decodeDrawFrame renders the draw commands of a notification or custom app body.
+// Only the pixel (dp), filled rectangle (df) and bitmap (db) commands are supported.
+func decodeDrawFrame(r *http.Request, width int, height int) (*Frame, error) {
+	var body struct {
+		Draw []map[string][]json.RawMessage `json:"draw"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := NewFrame(width, height)
+
+	for _, command := range body.Draw {
+		for name, args := range command {
+			nums, err := decodeDrawInts(args)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+
+			switch name {
+			case "dp":
+				if len(args) != 3 || len(nums) != 2 {
+					return nil, errors.New("dp: expected [x, y, color]")
+				}
+				c, err := decodeDrawColor(args[2])
+				if err != nil {
+					return nil, err
+				}
+				frame.setPixel(nums[0], nums[1], c)
+
+			case "df":
+				if len(args) != 5 || len(nums) != 4 {
+					return nil, errors.New("df: expected [x, y, w, h, color]")
+				}
+				c, err := decodeDrawColor(args[4])
+				if err != nil {
+					return nil, err
+				}
+				for y := nums[1]; y < nums[1]+nums[3]; y++ {
+					for x := nums[0]; x < nums[0]+nums[2]; x++ {
+						frame.setPixel(x, y, c)
+					}
+				}
+
+			case "db":
+				if len(args) != 5 || len(nums) != 4 || nums[2] <= 0 {
+					return nil, errors.New("db: expected [x, y, w, h, colors]")
+				}
+				var colors []json.RawMessage
+				err := json.Unmarshal(args[4], &colors)
+				if err != nil {
+					return nil, err
+				}
+				for i, raw := range colors {
+					c, err := decodeDrawColor(raw)
+					if err != nil {
+						return nil, err
+					}
+					frame.setPixel(nums[0]+i%nums[2], nums[1]+i/nums[2], c)
+				}
+			}
+		}
+	}
+
+	return frame, nil
+}
+
+// decodeDrawInts decodes the leading numeric arguments of a draw command, stopping at the first non-number.
+func decodeDrawInts(args []json.RawMessage) ([]int, error) {
+	nums := make([]int, 0, len(args))
+	for _, arg := range args {
+		var n int
+		if json.Unmarshal(arg, &n) != nil {
+			break
+		}
+		nums = append(nums, n)
+	}
+
+	if len(nums) == 0 {
+		return nil, errors.New("missing coordinates")
+	}
+
+	return nums, nil
+}
+
+// decodeDrawColor accepts the color forms awtrix does: a 24 bit integer, a "#RRGGBB" string, or an [r, g, b] array.
+func decodeDrawColor(raw json.RawMessage) ([3]uint8, error) {
+	var num uint32
+	if json.Unmarshal(raw, &num) == nil {
+		return [3]uint8{uint8(num >> 16), uint8(num >> 8), uint8(num)}, nil
+	}
+
+	var hex string
+	if json.Unmarshal(raw, &hex) == nil {
+		num, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 24)
+		if err != nil {
+			return [3]uint8{}, err
+		}
+		return [3]uint8{uint8(num >> 16), uint8(num >> 8), uint8(num)}, nil
+	}
+
+	var rgb [3]uint8
+	err := json.Unmarshal(raw, &rgb)
+	if err != nil {
+		return [3]uint8{}, fmt.Errorf("invalid color: %s", raw)
+	}
+
+	return rgb, nil
+}
+
+func (f *Frame) setPixel(x int, y int, c [3]uint8) {
+	if x < 0 || x >= f.Width || y < 0 || y >= f.Height {
+		return
+	}
+
+	f.Pixels[y*f.Width+x] = c
+}
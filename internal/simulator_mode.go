@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const simulatorRefreshInterval = time.Second / 30
+
+type SimulatorMode struct {
+	simulator *Simulator
+	address   string
+	err       error
+}
+
+func NewSimulatorMode(simulator *Simulator, address string) SimulatorMode {
+	return SimulatorMode{
+		simulator: simulator,
+		address:   address,
+	}
+}
+
+type simulatorRefreshMsg struct{}
+
+// SimulatorErrorMsg can be sent to the program when the simulator's HTTP server stops.
+type SimulatorErrorMsg struct {
+	Err error
+}
+
+func (m SimulatorMode) Init() tea.Cmd {
+	return refreshSimulator()
+}
+
+func (m SimulatorMode) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q":
+			return m, tea.Quit
+		}
+
+	case simulatorRefreshMsg:
+		return m, refreshSimulator()
+
+	case SimulatorErrorMsg:
+		m.err = msg.Err
+	}
+
+	return m, nil
+}
+
+func (m SimulatorMode) View() string {
+	var s strings.Builder
+
+	s.WriteString("\nawtrix simulator listening on ")
+	s.WriteString(m.address)
+	s.WriteString("\n\n")
+
+	screen := m.simulator.Screen()
+	s.WriteString(screen.View())
+	s.WriteRune('\n')
+
+	fmt.Fprintf(&s, "App: %s  Notification: %t  Frames received: %d\n", m.simulator.App(), m.simulator.NotificationActive(), m.simulator.ReceivedFrameCount())
+
+	if m.err != nil {
+		s.WriteString("Server error: ")
+		s.WriteString(m.err.Error())
+		s.WriteRune('\n')
+	}
+
+	s.WriteRune('\n')
+	for _, request := range m.simulator.Requests() {
+		s.WriteString(helpStyle(request))
+		s.WriteRune('\n')
+	}
+
+	s.WriteRune('\n')
+	s.WriteString(helpStyle("[q] quit\n"))
+
+	return s.String()
+}
+
+func refreshSimulator() tea.Cmd {
+	return tea.Tick(simulatorRefreshInterval, func(_ time.Time) tea.Msg {
+		return simulatorRefreshMsg{}
+	})
+}
@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+)
+
+const statsPollInterval = time.Second * 2
+const statsHistoryLength = 30
+
+type statsMetric struct {
+	label  string
+	value  func(s *DeviceStats) float64
+	format func(v float64) string
+}
+
+var statsMetrics = []statsMetric{
+	{"Battery", func(s *DeviceStats) float64 { return float64(s.Battery) }, func(v float64) string { return fmt.Sprintf("%.0f%%", v) }},
+	{"Light", func(s *DeviceStats) float64 { return s.Lux }, func(v float64) string { return fmt.Sprintf("%.0f lx", v) }},
+	{"Temperature", func(s *DeviceStats) float64 { return s.Temperature }, func(v float64) string { return fmt.Sprintf("%.1f°C", v) }},
+	{"Humidity", func(s *DeviceStats) float64 { return s.Humidity }, func(v float64) string { return fmt.Sprintf("%.0f%%", v) }},
+	{"Free heap", func(s *DeviceStats) float64 { return float64(s.FreeHeap) }, func(v float64) string { return humanize.IBytes(uint64(v)) }},
+	{"Wifi RSSI", func(s *DeviceStats) float64 { return float64(s.WifiSignal) }, func(v float64) string { return fmt.Sprintf("%.0f dBm", v) }},
+}
+
+// StatsPane polls /api/stats and keeps a short history of each metric for sparklines.
+type StatsPane struct {
+	stats   *DeviceStats
+	history [][]float64
+	err     error
+	// Each time the pane is opened it polls in a session of its own, so a poll still in flight from last time is let go
+	session int64
+}
+
+var statsSessions atomic.Int64
+
+func NewStatsPane() StatsPane {
+	return StatsPane{
+		history: make([][]float64, len(statsMetrics)),
+	}
+}
+
+type statsStartMsg struct {
+	session int64
+}
+
+type statsMsg struct {
+	session int64
+	stats   *DeviceStats
+	err     error
+	// Set for one-off refreshes so they don't start a second polling loop
+	refresh bool
+}
+
+type statsTickMsg struct {
+	session int64
+}
+
+// Init starts a new session, which starts over with an empty history. Copies of the pane share their history until then.
+func (p StatsPane) Init() tea.Cmd {
+	return func() tea.Msg {
+		return statsStartMsg{session: statsSessions.Add(1)}
+	}
+}
+
+func (p StatsPane) Update(msg tea.Msg) (StatsPane, tea.Cmd) {
+	switch msg := msg.(type) {
+	case statsStartMsg:
+		p = NewStatsPane()
+		p.session = msg.session
+		return p, p.fetchStats
+
+	case statsTickMsg:
+		if msg.session != p.session {
+			return p, nil
+		}
+		return p, p.fetchStats
+
+	case statsMsg:
+		if msg.session != p.session {
+			return p, nil
+		}
+
+		p.err = msg.err
+		if msg.err == nil {
+			p.stats = msg.stats
+			for i, metric := range statsMetrics {
+				history := append(p.history[i], metric.value(msg.stats))
+				if len(history) > statsHistoryLength {
+					history = history[len(history)-statsHistoryLength:]
+				}
+				p.history[i] = history
+			}
+		}
+
+		if msg.refresh {
+			return p, nil
+		}
+
+		session := p.session
+		return p, tea.Tick(statsPollInterval, func(_ time.Time) tea.Msg {
+			return statsTickMsg{session: session}
+		})
+	}
+
+	return p, nil
+}
+
+// App returns the name of the app the clock was last seen displaying.
+func (p StatsPane) App() string {
+	if p.stats == nil {
+		return ""
+	}
+
+	return p.stats.App
+}
+
+// Refresh fetches the stats immediately, outside of the regular polling.
+func (p StatsPane) Refresh() tea.Cmd {
+	return func() tea.Msg {
+		stats, err := GetDeviceStats()
+		return statsMsg{session: p.session, stats: stats, err: err, refresh: true}
+	}
+}
+
+func (p StatsPane) View() string {
+	var s strings.Builder
+
+	if p.stats == nil {
+		if p.err != nil {
+			s.WriteString(helpStyle("Stats unavailable: " + p.err.Error()))
+		} else {
+			s.WriteString(helpStyle("Loading stats..."))
+		}
+		s.WriteRune('\n')
+		return s.String()
+	}
+
+	for i, metric := range statsMetrics {
+		fmt.Fprintf(&s, "%-12s %10s  %s\n", metric.label, metric.format(metric.value(p.stats)), Sparkline(p.history[i]))
+	}
+
+	fmt.Fprintf(&s, "%-12s %10s\n", "Uptime", FmtDuration(time.Duration(p.stats.Uptime)*time.Second))
+	fmt.Fprintf(&s, "%-12s %10s\n", "Firmware", p.stats.Version)
+
+	if p.err != nil {
+		s.WriteString(helpStyle("Stats stale: " + p.err.Error()))
+		s.WriteRune('\n')
+	}
+
+	return s.String()
+}
+
+func (p StatsPane) fetchStats() tea.Msg {
+	stats, err := GetDeviceStats()
+	return statsMsg{session: p.session, stats: stats, err: err}
+}
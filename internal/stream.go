@@ -1,7 +1,7 @@
 package internal
 
 import (
-	"fmt"
+	"context"
 	"time"
 )
 
@@ -10,6 +10,8 @@ var Host string
 type PixelStream struct {
 	Version   uint8
 	FrameRate uint8
+	Width     int
+	Height    int
 	Frames    []Frame
 }
 
@@ -18,39 +20,53 @@ func (ps *PixelStream) GetTotalDuration() time.Duration {
 }
 
 func (ps *PixelStream) GetFrame(d time.Duration) *Frame {
-	frameNum := float64(ps.FrameRate) * d.Seconds()
+	return &ps.Frames[ps.frameIndex(d)]
+}
+
+// frameIndex returns the frame shown at d, clamped to the stream.
+func (ps *PixelStream) frameIndex(d time.Duration) int {
+	return min(max(int(int64(d)*int64(ps.FrameRate)/int64(time.Second)), 0), len(ps.Frames)-1)
+}
 
-	return &ps.Frames[int64(frameNum)]
+// frameTime returns when the frame at index starts, rounded up so frameIndex gives index back.
+func (ps *PixelStream) frameTime(index int) time.Duration {
+	return (time.Duration(index)*time.Second + time.Duration(ps.FrameRate) - 1) / time.Duration(ps.FrameRate)
 }
 
-func (ps *PixelStream) Stream(host string) {
-	notifyUrl := host + "/api/notify"
+// Resize returns the stream with every frame scaled to width x height.
+func (ps *PixelStream) Resize(width int, height int) *PixelStream {
+	if ps.Width == width && ps.Height == height {
+		return ps
+	}
 
-	currentFrame := 0
+	resized := &PixelStream{
+		Version:   ps.Version,
+		FrameRate: ps.FrameRate,
+		Width:     width,
+		Height:    height,
+		Frames:    make([]Frame, len(ps.Frames)),
+	}
 
-	ticker := time.NewTicker(time.Duration((1000 / int64(ps.FrameRate)) * int64(time.Millisecond)))
-	done := make(chan bool)
+	for i := range ps.Frames {
+		resized.Frames[i] = *ps.Frames[i].Resize(width, height)
+	}
 
-	go func() {
-		for {
-			select {
-			case <-done:
-				return
-			case t := <-ticker.C:
-				if currentFrame < len(ps.Frames) {
-					fmt.Println("Frame", currentFrame, "Time", t)
+	return resized
+}
 
-					ps.Frames[currentFrame].SendFrame(notifyUrl)
+// Stream plays the whole stream to output in real time, without a UI, and returns what happened to its frames.
+// Playback stops early if ctx is done, which is the only way it stops when looping.
+func (ps *PixelStream) Stream(ctx context.Context, output Transport, options PlaybackOptions) PlaybackStats {
+	player := NewPlayer(ps, output)
+	player.SetOptions(options)
+	player.Play()
 
-					currentFrame++
-				} else {
-					ticker.Stop()
-					done <- true
-					fmt.Println("Stream Done!")
-				}
-			}
-		}
-	}()
+	select {
+	case <-player.Ended():
+	case <-ctx.Done():
+	}
 
-	<-done
+	// Closed first so a frame that's still being sent is counted
+	player.Close()
+	return player.TotalStats()
 }
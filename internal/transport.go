@@ -0,0 +1,345 @@
+package internal
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// Transport sends frames to a display.
+type Transport interface {
+	Send(frame *Frame) error
+	Close() error
+	// String describes the transport for display, without any credentials
+	String() string
+}
+
+// Output is where PlayMode sends frames. When it's nil, frames go to the awtrix clock at Host.
+var Output Transport
+
+func output() Transport {
+	if Output != nil {
+		return Output
+	}
+
+	return &AwtrixTransport{Host: Host, Mapping: Mapping}
+}
+
+// NewTransport opens a transport for an output URL. http and https URLs are awtrix clocks, and
+// ddp://, e131:// and wled:// send to WLED (or anything else speaking those protocols) over UDP.
+// Every transport takes the pixel mapping query parameters described by ParsePixelMapping.
+func NewTransport(rawURL string) (Transport, error) {
+	outputURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping, err := ParsePixelMapping(outputURL.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	if outputURL.Scheme == "http" || outputURL.Scheme == "https" {
+		host, err := GetUrlHost(rawURL)
+		if err != nil {
+			return nil, err
+		}
+
+		host, err = WithEnvCredentials(host)
+		if err != nil {
+			return nil, err
+		}
+
+		return &AwtrixTransport{Host: host, Mapping: mapping}, nil
+	}
+
+	if outputURL.Hostname() == "" {
+		return nil, fmt.Errorf("missing host in output %q", rawURL)
+	}
+
+	switch outputURL.Scheme {
+	case "ddp":
+		conn, err := dialUDP(outputURL, ddpPort)
+		if err != nil {
+			return nil, err
+		}
+		return &DDPTransport{conn: conn, mapping: mapping}, nil
+
+	case "e131", "sacn":
+		universe := uint64(1)
+		if value := outputURL.Query().Get("universe"); value != "" {
+			universe, err = strconv.ParseUint(value, 10, 16)
+			if err != nil || universe < 1 || universe > 63999 {
+				return nil, fmt.Errorf("invalid universe %q, expected 1 to 63999", value)
+			}
+		}
+
+		conn, err := dialUDP(outputURL, e131Port)
+		if err != nil {
+			return nil, err
+		}
+
+		transport := &E131Transport{conn: conn, mapping: mapping, universe: uint16(universe)}
+		_, err = rand.Read(transport.cid[:])
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return transport, nil
+
+	case "wled":
+		timeout := uint64(wledDefaultTimeout)
+		if value := outputURL.Query().Get("timeout"); value != "" {
+			timeout, err = strconv.ParseUint(value, 10, 8)
+			if err != nil || timeout < 1 {
+				return nil, fmt.Errorf("invalid timeout %q, expected 1 to 255 seconds", value)
+			}
+		}
+
+		conn, err := dialUDP(outputURL, wledPort)
+		if err != nil {
+			return nil, err
+		}
+		return &WLEDTransport{conn: conn, mapping: mapping, timeout: uint8(timeout)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported output %q, expected http, https, ddp, e131 or wled", outputURL.Scheme)
+}
+
+func dialUDP(outputURL *url.URL, defaultPort int) (net.Conn, error) {
+	port := outputURL.Port()
+	if port == "" {
+		port = strconv.Itoa(defaultPort)
+	}
+
+	return net.Dial("udp", net.JoinHostPort(outputURL.Hostname(), port))
+}
+
+// pixelBytes flattens the pixels of frame into RGB triplets.
+func pixelBytes(frame *Frame) []byte {
+	data := make([]byte, 0, len(frame.Pixels)*3)
+	for _, pixel := range frame.Pixels {
+		data = append(data, pixel[:]...)
+	}
+
+	return data
+}
+
+type AwtrixTransport struct {
+	Host    string
+	Mapping PixelMapping
+}
+
+func (t *AwtrixTransport) Send(frame *Frame) error {
+	mapped, err := t.Mapping.Apply(frame)
+	if err != nil {
+		return err
+	}
+
+	return mapped.SendFrame(t.Host + "/api/notify")
+}
+
+// Close dismisses the stream, so the clock goes back to its apps.
+func (t *AwtrixTransport) Close() error {
+	return DismissNotification(t.Host)
+}
+
+func (t *AwtrixTransport) String() string {
+	return RedactHost(t.Host)
+}
+
+const ddpPort = 4048
+
+const (
+	ddpVersion1     = 0x40
+	ddpPush         = 0x01
+	ddpTypeRGB24    = 0x0B
+	ddpDisplayID    = 0x01
+	ddpHeaderLength = 10
+	// Keeps packets under a typical MTU, and is a whole number of pixels
+	ddpMaxData = 1440
+)
+
+// DDPTransport sends frames with the Distributed Display Protocol, which WLED listens for on port 4048.
+type DDPTransport struct {
+	conn     net.Conn
+	mapping  PixelMapping
+	sequence uint8
+}
+
+func (t *DDPTransport) Send(frame *Frame) error {
+	mapped, err := t.mapping.Apply(frame)
+	if err != nil {
+		return err
+	}
+	data := pixelBytes(mapped)
+
+	// Sequence numbers run from 1 to 15, 0 means they aren't used
+	t.sequence = t.sequence%15 + 1
+
+	for offset := 0; offset < len(data); offset += ddpMaxData {
+		chunk := data[offset:min(offset+ddpMaxData, len(data))]
+
+		packet := make([]byte, ddpHeaderLength, ddpHeaderLength+len(chunk))
+		packet[0] = ddpVersion1
+		// Only the last packet of a frame tells the display to show it
+		if offset+len(chunk) == len(data) {
+			packet[0] |= ddpPush
+		}
+		packet[1] = t.sequence
+		packet[2] = ddpTypeRGB24
+		packet[3] = ddpDisplayID
+		binary.BigEndian.PutUint32(packet[4:], uint32(offset))
+		binary.BigEndian.PutUint16(packet[8:], uint16(len(chunk)))
+		packet = append(packet, chunk...)
+
+		_, err := t.conn.Write(packet)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *DDPTransport) Close() error {
+	return t.conn.Close()
+}
+
+func (t *DDPTransport) String() string {
+	return "ddp://" + t.conn.RemoteAddr().String()
+}
+
+const e131Port = 5568
+
+const (
+	e131HeaderLength = 126
+	// 170 RGB pixels fit in the 512 channels of a universe
+	e131PixelsPerUniverse = 170
+	e131Priority          = 100
+	e131SourceName        = "pixelstream"
+)
+
+var e131PacketIdentifier = []byte("ASC-E1.17\x00\x00\x00")
+
+// E131Transport sends frames as E1.31 (sACN) DMX data, starting at universe and using as many universes as the frame needs.
+type E131Transport struct {
+	conn     net.Conn
+	mapping  PixelMapping
+	universe uint16
+	cid      [16]byte
+	sequence uint8
+}
+
+func (t *E131Transport) Send(frame *Frame) error {
+	mapped, err := t.mapping.Apply(frame)
+	if err != nil {
+		return err
+	}
+	data := pixelBytes(mapped)
+	universe := t.universe
+
+	for offset := 0; offset < len(data); offset += e131PixelsPerUniverse * 3 {
+		chunk := data[offset:min(offset+e131PixelsPerUniverse*3, len(data))]
+
+		_, err := t.conn.Write(t.packet(universe, chunk))
+		if err != nil {
+			return err
+		}
+
+		t.sequence++
+		universe++
+	}
+
+	return nil
+}
+
+func (t *E131Transport) packet(universe uint16, channels []byte) []byte {
+	length := e131HeaderLength + len(channels)
+	packet := make([]byte, e131HeaderLength, length)
+
+	// Root layer
+	binary.BigEndian.PutUint16(packet[0:], 0x0010)
+	copy(packet[4:], e131PacketIdentifier)
+	binary.BigEndian.PutUint16(packet[16:], 0x7000|uint16(length-16))
+	binary.BigEndian.PutUint32(packet[18:], 0x00000004)
+	copy(packet[22:], t.cid[:])
+
+	// Framing layer
+	binary.BigEndian.PutUint16(packet[38:], 0x7000|uint16(length-38))
+	binary.BigEndian.PutUint32(packet[40:], 0x00000002)
+	copy(packet[44:108], e131SourceName)
+	packet[108] = e131Priority
+	packet[111] = t.sequence
+	binary.BigEndian.PutUint16(packet[113:], universe)
+
+	// DMP layer
+	binary.BigEndian.PutUint16(packet[115:], 0x7000|uint16(length-115))
+	packet[117] = 0x02
+	packet[118] = 0xA1
+	binary.BigEndian.PutUint16(packet[121:], 0x0001)
+	binary.BigEndian.PutUint16(packet[123:], uint16(len(channels)+1))
+	// packet[125] is the DMX start code, always 0
+
+	return append(packet, channels...)
+}
+
+func (t *E131Transport) Close() error {
+	return t.conn.Close()
+}
+
+func (t *E131Transport) String() string {
+	return fmt.Sprintf("e131://%s (universe %d)", t.conn.RemoteAddr(), t.universe)
+}
+
+const wledPort = 21324
+
+const (
+	wledProtocolDNRGB = 4
+	// Seconds WLED waits after the last packet before going back to its own effects
+	wledDefaultTimeout     = 2
+	wledMaxPixelsPerPacket = 489
+)
+
+// WLEDTransport sends frames with WLED's UDP realtime protocol (DNRGB), on port 21324.
+type WLEDTransport struct {
+	conn    net.Conn
+	mapping PixelMapping
+	timeout uint8
+}
+
+func (t *WLEDTransport) Send(frame *Frame) error {
+	mapped, err := t.mapping.Apply(frame)
+	if err != nil {
+		return err
+	}
+	data := pixelBytes(mapped)
+
+	for offset := 0; offset < len(data); offset += wledMaxPixelsPerPacket * 3 {
+		chunk := data[offset:min(offset+wledMaxPixelsPerPacket*3, len(data))]
+
+		packet := make([]byte, 4, 4+len(chunk))
+		packet[0] = wledProtocolDNRGB
+		packet[1] = t.timeout
+		binary.BigEndian.PutUint16(packet[2:], uint16(offset/3))
+		packet = append(packet, chunk...)
+
+		_, err := t.conn.Write(packet)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *WLEDTransport) Close() error {
+	return t.conn.Close()
+}
+
+func (t *WLEDTransport) String() string {
+	return "wled://" + t.conn.RemoteAddr().String()
+}
@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// listenUDP starts a local UDP listener standing in for a WLED device, returning it and its address.
+func listenUDP(t *testing.T) (*net.UDPConn, string) {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, conn.LocalAddr().String()
+}
+
+// readPackets reads count packets from conn.
+func readPackets(t *testing.T, conn *net.UDPConn, count int) [][]byte {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second * 2))
+
+	packets := make([][]byte, count)
+	buf := make([]byte, 65536)
+	for i := range packets {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("expected %d packets, got %d: %s", count, i, err)
+		}
+		packets[i] = append([]byte(nil), buf[:n]...)
+	}
+
+	return packets
+}
+
+// newTestTransport opens the output URL, failing the test if it can't.
+func newTestTransport(t *testing.T, rawURL string) Transport {
+	t.Helper()
+
+	transport, err := NewTransport(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { transport.Close() })
+
+	return transport
+}
+
+// newGradientFrame returns a frame where every pixel is different, so reordering can be checked.
+func newGradientFrame(width int, height int) *Frame {
+	frame := NewFrame(width, height)
+	for i := range frame.Pixels {
+		frame.Pixels[i] = [3]uint8{uint8(i), uint8(i >> 8), 255}
+	}
+
+	return frame
+}
+
+func TestDDPTransport(t *testing.T) {
+	conn, addr := listenUDP(t)
+	transport := newTestTransport(t, "ddp://"+addr)
+
+	frame := newGradientFrame(32, 16)
+	err := transport.Send(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packets := readPackets(t, conn, 2)
+	var data []byte
+
+	for i, packet := range packets {
+		if packet[0]&0xC0 != ddpVersion1 || packet[2] != ddpTypeRGB24 || packet[3] != ddpDisplayID {
+			t.Errorf("packet %d: unexpected header % x", i, packet[:ddpHeaderLength])
+		}
+
+		if push := packet[0]&ddpPush != 0; push != (i == len(packets)-1) {
+			t.Errorf("packet %d: expected only the last packet to push, got push %t", i, push)
+		}
+
+		if offset := binary.BigEndian.Uint32(packet[4:]); int(offset) != len(data) {
+			t.Errorf("packet %d: expected offset %d, got %d", i, len(data), offset)
+		}
+
+		length := int(binary.BigEndian.Uint16(packet[8:]))
+		if length != len(packet)-ddpHeaderLength {
+			t.Errorf("packet %d: header length %d doesn't match %d data bytes", i, length, len(packet)-ddpHeaderLength)
+		}
+
+		data = append(data, packet[ddpHeaderLength:]...)
+	}
+
+	if !bytes.Equal(data, pixelBytes(frame)) {
+		t.Error("expected the packets to carry the frame's pixels in order")
+	}
+}
+
+func TestE131Transport(t *testing.T) {
+	conn, addr := listenUDP(t)
+	transport := newTestTransport(t, "e131://"+addr+"?universe=5")
+
+	frame := newGradientFrame(32, 16)
+	err := transport.Send(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 512 pixels need 4 universes of up to 170 pixels
+	packets := readPackets(t, conn, 4)
+	var data []byte
+
+	for i, packet := range packets {
+		if !bytes.Equal(packet[4:16], e131PacketIdentifier) {
+			t.Errorf("packet %d: missing the ACN packet identifier", i)
+		}
+
+		if universe := binary.BigEndian.Uint16(packet[113:]); universe != uint16(5+i) {
+			t.Errorf("packet %d: expected universe %d, got %d", i, 5+i, universe)
+		}
+
+		if rootLength := binary.BigEndian.Uint16(packet[16:]) & 0x0FFF; int(rootLength) != len(packet)-16 {
+			t.Errorf("packet %d: expected root layer length %d, got %d", i, len(packet)-16, rootLength)
+		}
+
+		channels := int(binary.BigEndian.Uint16(packet[123:])) - 1
+		if channels != len(packet)-e131HeaderLength || packet[125] != 0 {
+			t.Errorf("packet %d: expected %d channels after a zero start code, got %d", i, len(packet)-e131HeaderLength, channels)
+		}
+
+		data = append(data, packet[e131HeaderLength:]...)
+	}
+
+	if !bytes.Equal(data, pixelBytes(frame)) {
+		t.Error("expected the universes to carry the frame's pixels in order")
+	}
+}
+
+func TestWLEDTransport(t *testing.T) {
+	conn, addr := listenUDP(t)
+	transport := newTestTransport(t, "wled://"+addr+"?timeout=5")
+
+	frame := newGradientFrame(32, 16)
+	err := transport.Send(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packets := readPackets(t, conn, 2)
+	var data []byte
+
+	for i, packet := range packets {
+		if packet[0] != wledProtocolDNRGB || packet[1] != 5 {
+			t.Errorf("packet %d: expected DNRGB with a 5s timeout, got % x", i, packet[:2])
+		}
+
+		if start := binary.BigEndian.Uint16(packet[2:]); int(start) != len(data)/3 {
+			t.Errorf("packet %d: expected start index %d, got %d", i, len(data)/3, start)
+		}
+
+		data = append(data, packet[4:]...)
+	}
+
+	if !bytes.Equal(data, pixelBytes(frame)) {
+		t.Error("expected the packets to carry the frame's pixels in order")
+	}
+}
+
+func TestNewTransportErrors(t *testing.T) {
+	for _, rawURL := range []string{
+		"ftp://127.0.0.1",
+		"ddp://",
+		"ddp://127.0.0.1?flip=x",
+		"ddp://127.0.0.1?rotate=90",
+		"e131://127.0.0.1?universe=0",
+		"wled://127.0.0.1?timeout=0",
+	} {
+		transport, err := NewTransport(rawURL)
+		if err == nil {
+			transport.Close()
+			t.Errorf("expected %s to be rejected", rawURL)
+		}
+	}
+}
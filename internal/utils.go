@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"math"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,7 +26,44 @@ func GetUrlHost(rawURL string) (string, error) {
 		return "", errors.New("missing or invalid host")
 	}
 
-	return newURL.Scheme + "://" + newURL.Host, nil
+	// Keep any credentials, net/http sends them as basic auth
+	host := url.URL{
+		Scheme: newURL.Scheme,
+		User:   newURL.User,
+		Host:   newURL.Host,
+	}
+
+	return host.String(), nil
+}
+
+// WithCredentials adds basic auth credentials to host, unless it already has some or username is empty.
+func WithCredentials(host string, username string, password string) (string, error) {
+	hostURL, err := url.Parse(host)
+	if err != nil {
+		return "", err
+	}
+
+	if hostURL.User != nil || username == "" {
+		return host, nil
+	}
+
+	hostURL.User = url.UserPassword(username, password)
+	return hostURL.String(), nil
+}
+
+// WithEnvCredentials adds the credentials from PIXELSTREAM_USERNAME and PIXELSTREAM_PASSWORD to host, unless it already has some.
+func WithEnvCredentials(host string) (string, error) {
+	return WithCredentials(host, os.Getenv("PIXELSTREAM_USERNAME"), os.Getenv("PIXELSTREAM_PASSWORD"))
+}
+
+// RedactHost hides the password of host so it can be displayed.
+func RedactHost(host string) string {
+	hostURL, err := url.Parse(host)
+	if err != nil {
+		return host
+	}
+
+	return hostURL.Redacted()
 }
 
 func SwitchMode(mode tea.Model) (tea.Model, tea.Cmd) {
@@ -46,6 +85,62 @@ func FmtDuration(d time.Duration) string {
 	return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
 }
 
+// ParseTimestamp reads a position written as seconds, m:ss or h:mm:ss, where the seconds can have a fraction,
+// or as a duration such as 1m30s.
+func ParseTimestamp(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return d, nil
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q, expected h:mm:ss", s)
+	}
+
+	var minutes int64
+	for _, part := range parts[:len(parts)-1] {
+		n, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp %q, expected h:mm:ss", s)
+		}
+		minutes = minutes*60 + int64(n)
+	}
+
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil || seconds < 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
+		return 0, fmt.Errorf("invalid timestamp %q, expected h:mm:ss", s)
+	}
+
+	return time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second)), nil
+}
+
+var sparklineLevels = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values as a row of block characters scaled between their min and max.
+func Sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	low, high := values[0], values[0]
+	for _, v := range values {
+		low = min(low, v)
+		high = max(high, v)
+	}
+
+	var s strings.Builder
+	for _, v := range values {
+		level := 0
+		if high > low {
+			level = int((v - low) / (high - low) * float64(len(sparklineLevels)-1))
+		}
+		s.WriteRune(sparklineLevels[level])
+	}
+
+	return s.String()
+}
+
 var OS_FS = os.DirFS(OS_FS_ROOT)
 
 type FileLocation struct {
@@ -1,26 +1,62 @@
 package internal
 
 import (
-	"net/http"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
+var appListStyle = lipgloss.NewStyle().PaddingLeft(4)
+
+const DefaultViewPollInterval = time.Millisecond * 250
+const maxViewPollBackoff = time.Second * 30
+
+// Consecutive failed polls before the clock is shown as offline instead of reconnecting
+const viewOfflineAfter = 5
+
 type ViewMode struct {
 	currentFrame  *Frame
+	pollInterval  time.Duration
+	failures      int
+	lastErr       error
+	lastUpdate    time.Time
 	appSwitchLock *CmdLock
+	stats         StatsPane
+	showStats     bool
+	apps          []string
+	appsErr       error
+	selectedApp   int
+	recorder      *Recorder
+	status        string
+	// Each visit polls in a session of its own, so a poll still waiting from an earlier visit doesn't start a second loop
+	session int64
 }
 
-func NewViewMode() ViewMode {
+var viewSessions atomic.Int64
+
+func NewViewMode(pollInterval time.Duration) ViewMode {
 	return ViewMode{
-		currentFrame:  &Frame{},
+		currentFrame:  NewFrame(Device.Width, Device.Height),
+		pollInterval:  pollInterval,
 		appSwitchLock: &CmdLock{},
+		stats:         NewStatsPane(),
+		showStats:     true,
 	}
 }
 
 func (m ViewMode) Init() tea.Cmd {
-	return m.fetchFrame
+	start := func() tea.Msg {
+		return viewStartMsg{session: viewSessions.Add(1)}
+	}
+
+	return tea.Batch(start, m.stats.Init(), fetchAppLoop)
 }
 
 func (m ViewMode) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -31,27 +67,131 @@ func (m ViewMode) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 
 		case "q":
+			// Leaving would lose the recording, so it has to be stopped and saved first
+			if m.recorder != nil {
+				m.status = "Stop the recording with [c] before leaving"
+				return m, nil
+			}
 			return NewMenuMode(), nil
 
 		case "left":
-			return m, m.appSwitchLock.TryLock(func() tea.Msg {
-				http.Post(Host+"/api/previousapp", "application/json", http.NoBody)
+			return m, tea.Sequence(m.appSwitchLock.TryLock(func() tea.Msg {
+				apiPostJSON("/api/previousapp", nil)
 				return nil
-			})
+			}), m.stats.Refresh())
 
 		case "right":
-			return m, m.appSwitchLock.TryLock(func() tea.Msg {
-				http.Post(Host+"/api/nextapp", "application/json", http.NoBody)
+			return m, tea.Sequence(m.appSwitchLock.TryLock(func() tea.Msg {
+				apiPostJSON("/api/nextapp", nil)
 				return nil
-			})
+			}), m.stats.Refresh())
+
+		case "up", "k":
+			if m.selectedApp > 0 {
+				m.selectedApp--
+			}
+			return m, nil
+
+		case "down", "j":
+			if m.selectedApp < len(m.apps)-1 {
+				m.selectedApp++
+			}
+			return m, nil
+
+		case "enter":
+			if m.selectedApp >= len(m.apps) {
+				return m, nil
+			}
+			name := m.apps[m.selectedApp]
+			return m, tea.Sequence(m.appSwitchLock.TryLock(func() tea.Msg {
+				SwitchApp(name)
+				return nil
+			}), m.stats.Refresh())
+
+		case "r":
+			return m, fetchAppLoop
+
+		case "s":
+			m.showStats = !m.showStats
+			return m, nil
+
+		case "c":
+			if m.recorder == nil {
+				m.recorder = NewRecorder()
+				m.status = ""
+				return m, nil
+			}
+
+			recorder := m.recorder
+			m.recorder = nil
+			m.status = "Saving recording..."
+			return m, saveRecording(recorder)
+
+		case "p", "P":
+			frame := *m.currentFrame
+			dots := msg.String() == "P"
+			return m, func() tea.Msg {
+				path, err := timestampedPath("screenshot", ".png")
+				if err == nil {
+					err = frame.SavePNG(path, DefaultScreenshotScale, dots)
+				}
+				return fileSavedMsg{label: "screenshot", path: path, err: err}
+			}
 		}
 
+	case viewStartMsg:
+		m.session = msg.session
+		m.failures = 0
+		m.lastErr = nil
+		return m, m.fetchFrame
+
 	case fetchFrameMsg:
-		m.currentFrame = msg
+		if msg.session != m.session {
+			return m, nil
+		}
+
+		if msg.err != nil {
+			m.failures++
+			m.lastErr = msg.err
+			return m, pollFrameAfter(m.session, m.backoff())
+		}
+
+		m.currentFrame = msg.frame
+		m.failures = 0
+		m.lastErr = nil
+		m.lastUpdate = time.Now()
+		if m.recorder != nil {
+			m.recorder.Add(m.lastUpdate, *m.currentFrame)
+		}
+		return m, pollFrameAfter(m.session, m.pollInterval)
+
+	case pollFrameMsg:
+		if msg.session != m.session {
+			return m, nil
+		}
 		return m, m.fetchFrame
+
+	case fileSavedMsg:
+		if msg.err != nil {
+			m.status = "Failed to save " + msg.label + ": " + msg.err.Error()
+		} else {
+			m.status = "Saved " + msg.label + " to " + msg.path
+		}
+		return m, nil
+
+	case appLoopMsg:
+		m.appsErr = msg.err
+		if msg.err == nil {
+			m.apps = msg.apps
+			m.selectedApp = min(m.selectedApp, max(len(m.apps)-1, 0))
+		}
+		return m, nil
 	}
 
-	return m, nil
+	var cmd tea.Cmd
+	m.stats, cmd = m.stats.Update(msg)
+
+	return m, cmd
 }
 
 func (m ViewMode) View() string {
@@ -59,19 +199,159 @@ func (m ViewMode) View() string {
 
 	s.WriteRune('\n')
 
-	s.WriteString(m.currentFrame.View())
+	s.WriteString(m.connectionView())
+	s.WriteString("\n\n")
+
+	s.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, m.currentFrame.View(), m.appListView()))
 
 	s.WriteRune('\n')
 
-	s.WriteString(helpStyle("[q] quit  [←] prev slide  [→] next slide\n"))
+	if m.recorder != nil {
+		s.WriteString(fmt.Sprintf("● REC %s (%d frames captured)\n", FmtDuration(m.recorder.Elapsed()), m.recorder.FrameCount()))
+	}
+	if m.status != "" {
+		s.WriteString(m.status)
+		s.WriteRune('\n')
+	}
+	if m.recorder != nil || m.status != "" {
+		s.WriteRune('\n')
+	}
+
+	if m.showStats {
+		s.WriteString(m.stats.View())
+		s.WriteRune('\n')
+	}
+
+	s.WriteString(helpStyle("[q] quit  [←] prev slide  [→] next slide  [↑/↓] select app  [enter] switch  [r] reload apps  [s] stats  [c] record  [p/P] screenshot/LED screenshot\n"))
 
 	return s.String()
 }
 
-type fetchFrameMsg *Frame
+func (m ViewMode) appListView() string {
+	if m.appsErr != nil {
+		return appListStyle.Render(helpStyle("Apps unavailable: " + m.appsErr.Error()))
+	}
+
+	var s strings.Builder
+
+	for i, app := range m.apps {
+		if app == m.stats.App() {
+			app += " ●"
+		}
+
+		if i == m.selectedApp {
+			s.WriteString(selectedItemStyle.Render("> " + app))
+		} else {
+			s.WriteString(itemStyle.Render(app))
+		}
+		s.WriteRune('\n')
+	}
+
+	return appListStyle.Render(s.String())
+}
+
+type appLoopMsg struct {
+	apps []string
+	err  error
+}
+
+func fetchAppLoop() tea.Msg {
+	apps, err := GetAppLoop()
+	return appLoopMsg{apps: apps, err: err}
+}
+
+func (m ViewMode) connectionView() string {
+	var s strings.Builder
+
+	switch {
+	case m.failures >= viewOfflineAfter:
+		s.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render("● offline"))
+	case m.failures > 0:
+		s.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render("● reconnecting"))
+	case m.lastUpdate.IsZero():
+		s.WriteString(helpStyle("● connecting"))
+	default:
+		s.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render("● connected"))
+	}
+
+	if !m.lastUpdate.IsZero() {
+		s.WriteString(helpStyle("  last update " + m.lastUpdate.Format("15:04:05")))
+	}
+
+	if m.lastErr != nil {
+		s.WriteString(helpStyle("  " + m.lastErr.Error()))
+	}
+
+	return s.String()
+}
+
+// backoff doubles the poll interval for every consecutive failure, up to maxViewPollBackoff.
+func (m ViewMode) backoff() time.Duration {
+	delay := m.pollInterval
+	for i := 0; i < m.failures && delay < maxViewPollBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxViewPollBackoff)
+}
+
+type viewStartMsg struct {
+	session int64
+}
+
+type pollFrameMsg struct {
+	session int64
+}
+
+func pollFrameAfter(session int64, d time.Duration) tea.Cmd {
+	return tea.Tick(d, func(_ time.Time) tea.Msg {
+		return pollFrameMsg{session: session}
+	})
+}
+
+type fetchFrameMsg struct {
+	session int64
+	frame   *Frame
+	err     error
+}
 
 func (m ViewMode) fetchFrame() tea.Msg {
-	m.currentFrame.ReceiveFrame(Host + "/api/screen")
+	frame, err := ReadScreen(Host, Device, Mapping)
+	if err != nil {
+		return fetchFrameMsg{session: m.session, err: err}
+	}
 
-	return fetchFrameMsg(m.currentFrame)
+	return fetchFrameMsg{session: m.session, frame: frame}
+}
+
+type fileSavedMsg struct {
+	label string
+	path  string
+	err   error
+}
+
+// timestampedPath returns a path in the working directory like prefix-20060102-150405.ext
+func timestampedPath(prefix string, ext string) (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, prefix+"-"+time.Now().Format("20060102-150405")+ext), nil
+}
+
+func saveRecording(recorder *Recorder) tea.Cmd {
+	return func() tea.Msg {
+		if recorder.FrameCount() == 0 {
+			return fileSavedMsg{label: "recording", err: errors.New("no frames were captured")}
+		}
+
+		path, err := timestampedPath("recording", pixelstreamFileExt)
+		if err != nil {
+			return fileSavedMsg{label: "recording", err: err}
+		}
+
+		err = recorder.PixelStream(DefaultFrameRate).SaveFile(FromOSPath(path))
+		return fileSavedMsg{label: "recording", path: path, err: err}
+	}
 }
@@ -0,0 +1,205 @@
+package internal
+
+import (
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestViewModeMirrorsScreen(t *testing.T) {
+	newTestClock(t)
+
+	frame := newTestStream(4, 16).Frames[3]
+	err := frame.SendFrame(Host + "/api/notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewViewMode(time.Millisecond)
+	model, _ := m.Update(m.fetchFrame())
+	m = model.(ViewMode)
+
+	if !m.currentFrame.Equal(&frame) {
+		t.Error("expected the view to show the clock's screen")
+	}
+
+	view := m.View()
+	if !strings.Contains(view, "connected") || !strings.Contains(view, "last update") {
+		t.Errorf("expected a connected status, got:\n%s", view)
+	}
+}
+
+func TestViewModeBacksOffWhenOffline(t *testing.T) {
+	server := httptest.NewServer(NewSimulator())
+	server.Close()
+
+	previousHost := Host
+	Host = server.URL
+	t.Cleanup(func() { Host = previousHost })
+
+	m := NewViewMode(time.Millisecond * 100)
+
+	model, _ := m.Update(m.fetchFrame())
+	m = model.(ViewMode)
+
+	if !strings.Contains(m.View(), "reconnecting") {
+		t.Errorf("expected a reconnecting status after a failed poll, got:\n%s", m.View())
+	}
+
+	if m.backoff() != time.Millisecond*200 {
+		t.Errorf("expected the poll delay to double after a failure, got %s", m.backoff())
+	}
+
+	for i := 1; i < viewOfflineAfter+10; i++ {
+		model, _ = m.Update(m.fetchFrame())
+		m = model.(ViewMode)
+	}
+
+	if !strings.Contains(m.View(), "offline") {
+		t.Errorf("expected an offline status after repeated failures, got:\n%s", m.View())
+	}
+
+	if m.backoff() != maxViewPollBackoff {
+		t.Errorf("expected the poll delay to be capped at %s, got %s", maxViewPollBackoff, m.backoff())
+	}
+}
+
+func TestViewModeStartsOverEachVisit(t *testing.T) {
+	newTestClock(t)
+
+	// The menu keeps one copy of the mode, which every visit starts from
+	stored := NewViewMode(time.Millisecond)
+
+	visit := func() (ViewMode, tea.Cmd) {
+		t.Helper()
+
+		model, cmd := stored.Update(viewStartMsg{session: viewSessions.Add(1)})
+		model, cmd = model.Update(runCmd(cmd)[0])
+		if cmd == nil {
+			t.Fatal("expected the screen to be polled again")
+		}
+
+		return model.(ViewMode), cmd
+	}
+
+	first, _ := visit()
+	second, _ := visit()
+
+	// The poll that was waiting when the first visit ended doesn't start a second loop
+	if _, cmd := second.Update(pollFrameMsg{session: first.session}); cmd != nil {
+		t.Error("expected a poll from an earlier visit to be let go")
+	}
+	if _, cmd := second.Update(first.fetchFrame()); cmd != nil {
+		t.Error("expected a fetch from an earlier visit to be let go")
+	}
+	if _, cmd := second.Update(pollFrameMsg{session: second.session}); cmd == nil {
+		t.Error("expected the current visit's poll to fetch the screen")
+	}
+}
+
+func TestViewModeKeepsRecordingUntilSaved(t *testing.T) {
+	newTestClock(t)
+
+	// Recordings are saved in the working directory
+	wd, _ := os.Getwd()
+	err := os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	m := NewViewMode(time.Millisecond)
+	model, _ := update(m, keyPress("c"), m.fetchFrame())
+
+	model, _ = model.Update(keyPress("q"))
+	if _, ok := model.(ViewMode); !ok {
+		t.Fatalf("expected q not to leave while recording, got %T", model)
+	}
+	if view := model.View(); !strings.Contains(view, "● REC") || !strings.Contains(view, "Stop the recording") {
+		t.Errorf("expected to be told to stop the recording, got:\n%s", view)
+	}
+
+	model, cmd := model.Update(keyPress("c"))
+	model, _ = update(model, runCmd(cmd)...)
+	if view := model.View(); !strings.Contains(view, "Saved recording to") {
+		t.Fatalf("expected the recording to be saved, got:\n%s", view)
+	}
+
+	model, _ = model.Update(keyPress("q"))
+	if _, ok := model.(MenuMode); !ok {
+		t.Errorf("expected q to leave once the recording is saved, got %T", model)
+	}
+}
+
+func TestViewModeSwitchesApps(t *testing.T) {
+	clock := newTestClock(t)
+
+	var model tea.Model = NewViewMode(time.Millisecond)
+	model, _ = update(model, fetchAppLoop(), keyPress("down"), keyPress("down"))
+
+	model, cmd := model.Update(keyPress("enter"))
+	model, _ = update(model, runCmd(cmd)...)
+
+	if clock.App() != "Temperature" {
+		t.Errorf("expected enter to switch to the selected app, got %s", clock.App())
+	}
+
+	if !strings.Contains(model.View(), "Temperature ●") {
+		t.Errorf("expected the current app to be marked, got:\n%s", model.View())
+	}
+
+	model, cmd = model.Update(keyPress("right"))
+	model, _ = update(model, runCmd(cmd)...)
+
+	if clock.App() != "Humidity" {
+		t.Errorf("expected right to go to the next app, got %s", clock.App())
+	}
+
+	_, cmd = model.Update(keyPress("left"))
+	runCmd(cmd)
+
+	if clock.App() != "Temperature" {
+		t.Errorf("expected left to go to the previous app, got %s", clock.App())
+	}
+}
+
+func TestStatsPaneStartsOverEachVisit(t *testing.T) {
+	newTestClock(t)
+
+	// The menu keeps one copy of the pane, which every visit starts from
+	stored := NewStatsPane()
+
+	visit := func() (StatsPane, tea.Cmd) {
+		t.Helper()
+
+		p, cmd := stored.Update(runCmd(stored.Init())[0])
+		p, cmd = p.Update(runCmd(cmd)[0])
+		if len(p.history[0]) != 1 || p.stats == nil {
+			t.Fatalf("expected one reading per metric, got %v", p.history)
+		}
+
+		return p, cmd
+	}
+
+	first, tick := visit()
+	if tick == nil {
+		t.Fatal("expected the stats to be polled again")
+	}
+
+	second, _ := visit()
+	if len(stored.history[0]) != 0 {
+		t.Errorf("expected the stored pane's history to be left alone, got %v", stored.history)
+	}
+
+	// The poll that was waiting when the first visit ended doesn't start a second loop
+	if _, cmd := second.Update(statsTickMsg{session: first.session}); cmd != nil {
+		t.Error("expected a tick from an earlier visit to be let go")
+	}
+	if _, cmd := second.Update(statsTickMsg{session: second.session}); cmd == nil {
+		t.Error("expected the current visit's tick to poll")
+	}
+}
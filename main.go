@@ -1,11 +1,21 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"flag"
 	"fmt"
 	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"pixelstream/internal"
+	"slices"
+	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -14,19 +24,73 @@ import (
 var samplesFS embed.FS
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Error: host expected but not given. Use the following format:")
-		fmt.Println("\tpixelstream <host>")
+	if len(os.Args) >= 2 {
+		switch os.Args[1] {
+		case "relay":
+			relay(os.Args[2:])
+			return
+		case "screenshot":
+			screenshot(os.Args[2:])
+			return
+		case "simulate":
+			simulate(os.Args[2:])
+			return
+		case "discover":
+			discover(os.Args[2:])
+			return
+		case "play":
+			play(os.Args[2:])
+			return
+		}
+	}
+
+	pollInterval := flag.Duration("poll", internal.DefaultViewPollInterval, "how often the clock's screen is polled in View Screen")
+	output := flag.String("output", "", "send played videos here instead of the clock, such as ddp://192.168.1.50, e131:// or wled://")
+	flag.BoolVar(&internal.AdaptiveFrameRate, "adaptive", false, "lower the frame rate while the clock can't keep up")
+	flag.StringVar(&internal.AudioPlayer, "audio", "", "play the soundtrack of videos with this player, ffplay or mpv")
+	flag.DurationVar(&internal.AudioOffset, "audio-offset", 0, "delay the audio by this much to line it up with the LEDs, negative to play it early")
+	mpvSocket := flag.String("mpv-socket", internal.DefaultMPVSocket, "the IPC socket of the mpv player followed in Follow mpv")
+	history := ""
+	if configDir, err := os.UserConfigDir(); err == nil {
+		history = filepath.Join(configDir, "pixelstream", "history.json")
+	}
+	flag.StringVar(&internal.HistoryFile, "history", history, "remember where played videos were left in this file, empty to turn it off")
+	applyConnectionFlags := connectionFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Println("Use the following format:")
+		fmt.Println("\tpixelstream [-poll 250ms] [-size 32x8] [-adaptive] [-audio ffplay] [-output ddp://192.168.1.50] [host]")
 		fmt.Println("\tpixelstream http://192.168.1.170")
+		fmt.Println("\tpixelstream relay [-fps 4] [-skip-unchanged=false] <source host> <target host or output>...")
+		fmt.Println("\tpixelstream screenshot [-out screenshot.png] [-scale 16] [-dots] <host>")
+		fmt.Println("\tpixelstream simulate [-addr 127.0.0.1:7000] [-size 32x8] [-record received.pxlstrm]")
+		fmt.Println("\tpixelstream discover [-scan] [-timeout 3s]")
+		fmt.Println("\tpixelstream play [-adaptive] [-loop] [-speed 1] [-reverse] [-output ddp://192.168.1.50] <file> [host]")
+		fmt.Println("If no host is given, clocks on the local network are searched for.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *pollInterval <= 0 {
+		fmt.Println("Error: -poll must be a positive duration, such as 250ms or 1s")
 		os.Exit(1)
 	}
 
-	host, err := internal.GetUrlHost(os.Args[1])
-	if err != nil {
-		panic(err)
+	applyConnectionFlags()
+
+	var startMode tea.Model = internal.NewMenuMode()
+	if flag.NArg() < 1 && *output == "" {
+		startMode = internal.NewDiscoverMode()
+	} else if flag.NArg() > 0 {
+		internal.Host = parseHost(flag.Arg(0))
+		internal.Device = detectDevice(internal.Host)
 	}
 
-	internal.Host = host
+	// Videos are converted for the output's size when there is one, since that's what they're played on
+	if *output != "" {
+		internal.Output = parseOutput(*output)
+		internal.Device = detectOutputDevice(internal.Output, *output)
+		defer internal.Output.Close()
+	}
 
 	homeDirPath, err := os.UserHomeDir()
 	if err != nil {
@@ -40,15 +104,344 @@ func main() {
 	}
 
 	menuItems := []internal.MenuItem{
-		{Label: "View Screen", Mode: internal.NewViewMode()},
+		{Label: "View Screen", Mode: internal.NewViewMode(*pollInterval)},
 		{Label: "Play Video", Mode: internal.NewOpenFileMode(homeDirFL.System, homeDirFL.Path)},
 		{Label: "Play Sample", Mode: internal.NewOpenFileMode(samplesSubFS, ".")},
+		{Label: "Recent", Mode: internal.NewRecentMode()},
+		{Label: "Follow mpv", Mode: internal.NewFollowMode(*mpvSocket)},
+		{Label: "Control Panel", Mode: internal.NewControlMode()},
+	}
+
+	// With only an output there's no clock to view or control
+	if internal.Host == "" && *output != "" {
+		menuItems = slices.DeleteFunc(menuItems, func(item internal.MenuItem) bool {
+			return item.Label == "View Screen" || item.Label == "Control Panel"
+		})
 	}
 
 	internal.MenuItems = menuItems
 
-	_, err = tea.NewProgram(internal.NewMenuMode()).Run()
+	// Bubble Tea turns ctrl+c and SIGTERM into a quit, so the clock is restored however the program ends.
+	// A signal quits without the mode stopping its player, so that's done first, or a frame being sent could reappear after the restore.
+	model, err := tea.NewProgram(startMode).Run()
+	if mode, ok := model.(interface{ Close() }); ok {
+		mode.Close()
+	}
+	internal.StopPreconverting()
+	internal.RestoreDeviceState()
+	if err != nil {
+		panic(err)
+	}
+}
+
+func relay(args []string) {
+	flags := flag.NewFlagSet("relay", flag.ExitOnError)
+	fps := flags.Float64("fps", 4, "how many times per second the source screen is polled")
+	skipUnchanged := flags.Bool("skip-unchanged", true, "only send frames that differ from the last one sent")
+	applyConnectionFlags := connectionFlags(flags)
+	flags.Parse(args)
+
+	if flags.NArg() < 2 || *fps <= 0 {
+		fmt.Println("Error: a source and at least one target host are expected. Use the following format:")
+		fmt.Println("\tpixelstream relay [-fps 4] [-skip-unchanged=false] <source host> <target host or output>...")
+		fmt.Println("\tpixelstream relay http://192.168.1.170 http://192.168.1.171 ddp://192.168.1.50?serpentine=true")
+		os.Exit(1)
+	}
+
+	applyConnectionFlags()
+
+	source := parseHost(flags.Arg(0))
+	sizes := []internal.DeviceInfo{detectDevice(source)}
+
+	var targets []internal.Transport
+	for _, arg := range flags.Args()[1:] {
+		target := parseOutput(arg)
+		targets = append(targets, target)
+		sizes = append(sizes, detectOutputDevice(target, arg))
+	}
+
+	interval := time.Duration(float64(time.Second) / *fps)
+
+	_, err := tea.NewProgram(internal.NewRelayMode(source, targets, sizes, interval, *skipUnchanged)).Run()
+	for _, target := range targets {
+		target.Close()
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+func screenshot(args []string) {
+	flags := flag.NewFlagSet("screenshot", flag.ExitOnError)
+	out := flags.String("out", "screenshot.png", "path of the PNG file to write")
+	scale := flags.Int("scale", internal.DefaultScreenshotScale, "size in image pixels of each clock pixel")
+	dots := flags.Bool("dots", false, "draw pixels as round LEDs instead of squares")
+	applyConnectionFlags := connectionFlags(flags)
+	flags.Parse(args)
+
+	if flags.NArg() < 1 || *scale < 1 {
+		fmt.Println("Error: host expected but not given. Use the following format:")
+		fmt.Println("\tpixelstream screenshot [-out screenshot.png] [-scale 16] [-dots] <host>")
+		fmt.Println("\tpixelstream screenshot -out clock.png http://192.168.1.170")
+		os.Exit(1)
+	}
+
+	applyConnectionFlags()
+	host := parseHost(flags.Arg(0))
+
+	frame, err := internal.ReadScreen(host, detectDevice(host), internal.Mapping)
+	if err != nil {
+		fmt.Println("Error: failed to read the clock's screen:", err)
+		os.Exit(1)
+	}
+
+	err = frame.SavePNG(*out, *scale, *dots)
+	if err != nil {
+		fmt.Println("Error: failed to save screenshot:", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Saved screenshot to", *out)
+}
+
+func simulate(args []string) {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	addr := flags.String("addr", "127.0.0.1:7000", "address the simulated clock listens on")
+	record := flags.String("record", "", "save the frames the simulated clock received to this .pxlstrm file on exit")
+	size := flags.String("size", "32x8", "matrix size of the simulated clock, as WxH")
+	flags.Parse(args)
+
+	device, err := internal.ParseDeviceSize(*size)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Println("Error: failed to start the simulator:", err)
+		os.Exit(1)
+	}
+
+	simulator := internal.NewSimulatorWithSize(device.Width, device.Height)
+	program := tea.NewProgram(internal.NewSimulatorMode(simulator, "http://"+listener.Addr().String()))
+
+	go func() {
+		err := http.Serve(listener, simulator)
+		program.Send(internal.SimulatorErrorMsg{Err: err})
+	}()
+
+	_, err = program.Run()
+	listener.Close()
+	if err != nil {
+		panic(err)
+	}
+
+	if *record != "" {
+		recording := simulator.Recording(internal.DefaultFrameRate)
+		if len(recording.Frames) == 0 {
+			fmt.Println("No frames were received, nothing to save")
+			return
+		}
+
+		path, err := filepath.Abs(*record)
+		if err != nil {
+			panic(err)
+		}
+
+		err = recording.SaveFile(internal.FromOSPath(path))
+		if err != nil {
+			fmt.Println("Error: failed to save recording:", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Saved", len(recording.Frames), "frames to", path)
+	}
+}
+
+func discover(args []string) {
+	flags := flag.NewFlagSet("discover", flag.ExitOnError)
+	scan := flags.Bool("scan", false, "also probe every address on the local networks")
+	timeout := flags.Duration("timeout", 0, "how long to search for, by default 3s or as long as -scan takes")
+	applyConnectionFlags := connectionFlags(flags)
+	flags.Parse(args)
+
+	applyConnectionFlags()
+
+	if *timeout <= 0 {
+		*timeout = internal.DefaultDiscoverTimeout
+		if *scan {
+			*timeout = internal.ScanTimeout()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	clocks, err := internal.Discover(ctx, *scan)
+	if err != nil && len(clocks) == 0 {
+		fmt.Println("Error: discovery failed:", err)
+		os.Exit(1)
+	} else if err != nil {
+		fmt.Println("Warning:", err)
+	}
+
+	if len(clocks) == 0 {
+		fmt.Println("No clocks found")
+		if !*scan {
+			fmt.Println("Try again with -scan to probe every address on the local network")
+		}
+		os.Exit(1)
+	}
+
+	for _, clock := range clocks {
+		fmt.Printf("%s\t%s\n", clock.Host, clock.Name)
+	}
+}
+
+func play(args []string) {
+	flags := flag.NewFlagSet("play", flag.ExitOnError)
+	output := flags.String("output", "", "play to this output instead of a clock, such as ddp://192.168.1.50")
+	flags.BoolVar(&internal.AdaptiveFrameRate, "adaptive", false, "lower the frame rate while the clock can't keep up")
+	var options internal.PlaybackOptions
+	flags.BoolVar(&options.Loop, "loop", false, "start over at the end, until stopped with ctrl+c")
+	flags.DurationVar(&options.RepeatFrom, "repeat-from", 0, "with -repeat-to, play the part of the video between them over and over")
+	flags.DurationVar(&options.RepeatTo, "repeat-to", 0, "the end of the part played over and over")
+	flags.Float64Var(&options.Speed, "speed", 1, "play faster or slower, from 0.25 to 4")
+	flags.BoolVar(&options.Reverse, "reverse", false, "play backwards")
+	applyConnectionFlags := connectionFlags(flags)
+	flags.Parse(args)
+
+	if flags.NArg() < 1 || (flags.NArg() < 2 && *output == "") {
+		fmt.Println("Error: a file and a host or output are expected. Use the following format:")
+		fmt.Println("\tpixelstream play [-adaptive] [-loop] [-speed 1] [-reverse] [-output ddp://192.168.1.50] <file> [host]")
+		fmt.Println("\tpixelstream play video.mp4 http://192.168.1.170")
+		os.Exit(1)
+	}
+
+	if options.Speed < internal.MinSpeed || options.Speed > internal.MaxSpeed {
+		fmt.Println("Error: -speed must be between 0.25 and 4")
+		os.Exit(1)
+	}
+
+	applyConnectionFlags()
+
+	var transport internal.Transport
+	var device internal.DeviceInfo
+	if *output != "" {
+		transport = parseOutput(*output)
+		device = detectOutputDevice(transport, *output)
+	} else {
+		internal.Host = parseHost(flags.Arg(1))
+		transport = &internal.AwtrixTransport{Host: internal.Host, Mapping: internal.Mapping}
+		device = detectDevice(internal.Host)
+	}
+
+	path, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		panic(err)
+	}
+
+	pixelstream, err := internal.LoadOrGenerate(internal.FromOSPath(path), device.Width, device.Height)
+	if err != nil {
+		fmt.Println("Error: failed to load", path+":", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *output == "" {
+		internal.SaveDeviceState()
+	}
+
+	fmt.Println("Playing", path, "on", transport)
+	stats := pixelstream.Resize(device.Width, device.Height).Stream(ctx, transport, options)
+
+	if *output == "" {
+		internal.RestoreDeviceState()
+	} else {
+		transport.Close()
+	}
+
+	fmt.Println("Done.", stats)
+}
+
+// connectionFlags registers the flags shared by every command that talks to a clock and returns a function that applies them.
+func connectionFlags(flags *flag.FlagSet) func() {
+	caCert := flags.String("ca-cert", "", "PEM file of a certificate authority to trust for HTTPS clocks")
+	insecure := flags.Bool("insecure", false, "skip certificate verification for HTTPS clocks")
+	size := flags.String("size", "", "matrix size of the clock as WxH, instead of probing it")
+	mapping := flags.String("mapping", "", "how the clock's matrix is mounted and wired, such as rotate=180&flip=h&serpentine=rows or lut=leds.txt")
+
+	return func() {
+		err := internal.ConfigureTLS(*caCert, *insecure)
+		if err != nil {
+			fmt.Println("Error: failed to configure TLS:", err)
+			os.Exit(1)
+		}
+
+		if *size != "" {
+			internal.DeviceSizeOverride, err = internal.ParseDeviceSize(*size)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+
+		query, err := url.ParseQuery(*mapping)
+		if err == nil {
+			internal.Mapping, err = internal.ParsePixelMapping(query)
+		}
+		if err != nil {
+			fmt.Println("Error: invalid -mapping:", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// detectDevice returns the matrix size of the clock at host, warning and assuming 32x8 if it can't be probed.
+func detectDevice(host string) internal.DeviceInfo {
+	device, err := internal.DetectDevice(host)
+	if err != nil {
+		fmt.Printf("Warning: couldn't probe the matrix size of %s, assuming %s: %s\n", internal.RedactHost(host), device, err)
+	}
+
+	return device
+}
+
+// detectOutputDevice returns the matrix size of an output. A clock is probed at the host its transport sends to,
+// which has the credentials and none of the pixel mapping query.
+func detectOutputDevice(transport internal.Transport, arg string) internal.DeviceInfo {
+	if awtrix, ok := transport.(*internal.AwtrixTransport); ok {
+		return detectDevice(awtrix.Host)
+	}
+
+	return detectDevice(arg)
+}
+
+// parseOutput opens an output argument, see internal.NewTransport.
+func parseOutput(arg string) internal.Transport {
+	transport, err := internal.NewTransport(arg)
+	if err != nil {
+		fmt.Println("Error: invalid output:", err)
+		os.Exit(1)
+	}
+
+	return transport
+}
+
+// parseHost validates a host argument. Hosts without credentials use PIXELSTREAM_USERNAME and PIXELSTREAM_PASSWORD, if set.
+func parseHost(arg string) string {
+	host, err := internal.GetUrlHost(arg)
+	if err != nil {
+		panic(err)
+	}
+
+	host, err = internal.WithEnvCredentials(host)
 	if err != nil {
 		panic(err)
 	}
+
+	return host
 }